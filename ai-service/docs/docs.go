@@ -37,12 +37,12 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "the topic for the words to generate",
+                        "description": "the topic, number and difficulty of the words to generate",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.TextManipulationRequest"
+                            "$ref": "#/definitions/handlers.GuessWordsRequest"
                         }
                     }
                 ],
@@ -77,6 +77,68 @@ const docTemplate = `{
                 }
             }
         },
+        "/quiz": {
+            "post": {
+                "description": "Gives multiple-choice questions with four options for that specific topic",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ai"
+                ],
+                "summary": "Gives multiple-choice questions for that specific topic",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "the topic and number of the questions to generate",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.QuizRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "generated questions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.QuizQuestion"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or user ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
         "/version": {
             "get": {
                 "summary": "Get the service Version",
@@ -92,14 +154,48 @@ const docTemplate = `{
         }
     },
     "definitions": {
-        "handlers.TextManipulationRequest": {
+        "handlers.GuessWordsRequest": {
             "type": "object",
             "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "difficulty": {
+                    "type": "string"
+                },
                 "text": {
                     "type": "string"
                 }
             }
         },
+        "handlers.QuizRequest": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.QuizQuestion": {
+            "type": "object",
+            "properties": {
+                "answer": {
+                    "type": "integer"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "question": {
+                    "type": "string"
+                }
+            }
+        },
         "utils.ServiceError": {
             "type": "object",
             "properties": {
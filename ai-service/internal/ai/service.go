@@ -3,6 +3,7 @@ package ai
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
@@ -18,9 +19,20 @@ const (
 	SUMMARIZE_PROMPT = "Make a one sentence summary:\n"
 	CORRECT_PROMPT   = "Fix spelling and grammar. Don't say what you did. Return only new version:\n\n"
 	REWRITE_PROMPT   = "Rephrase this text, no matter what. Don't say what you did. Return only new version:\n\n"
-	GEUSS_PROMPT     = "Generate a JSON list of names for that topic. Your response is ALWAYS an array of strings. Nothing else.\n\n Topic:"
+	GEUSS_PROMPT     = "Generate a JSON list of exactly %d %s names for that topic. Your response is ALWAYS an array of strings. Nothing else.\n\n Topic:"
+	GUESS_WORDS      = 10
+	QUIZ_PROMPT      = "Generate exactly %d multiple-choice quiz questions about that topic. Every question has exactly four short options and exactly one of them is correct. Answer is the index of the correct option, starting at 0.\n\n Topic:"
+	QUIZ_QUESTIONS   = 5
+	QUIZ_OPTIONS     = 4
 )
 
+// difficulties describes how well known the generated words should be
+var difficulties = map[string]string{
+	"easy":   "well known",
+	"medium": "fairly well known",
+	"hard":   "rather unknown",
+}
+
 var options = map[string]interface{}{
 	"num_predict": 1024,
 }
@@ -58,27 +70,37 @@ func (ai *AiService) manipulateTextByPrompt(line, prompt string, answerFunc api.
 	return nil
 }
 
-func (ai *AiService) GenerateGuessWords(topic string) ([]string, error) {
+func (ai *AiService) GenerateGuessWords(topic string, count int, difficulty string) ([]string, error) {
+	if count <= 0 {
+		count = GUESS_WORDS
+	}
+
+	level, ok := difficulties[difficulty]
+	if !ok {
+		level = difficulties["medium"]
+	}
+
 	var (
 		ctx = context.Background()
 		req = &api.GenerateRequest{
 			Model:   SMALL_MODEL,
-			Prompt:  GEUSS_PROMPT + topic,
+			Prompt:  fmt.Sprintf(GEUSS_PROMPT, count, level) + topic,
 			Options: options,
 			Stream:  new(bool),
-			Format: []byte(`{
+			Format: []byte(fmt.Sprintf(`{
 				"type": "object",
 				"properties": {
 					"words": {
 						"type": "array",
-						"maxItems": 10,
+						"minItems": %d,
+						"maxItems": %d,
 						"items": {
 							"type": "string"
 						}
 					}
 				},
 				"required": ["words"]
-			}`),
+			}`, count, count)),
 		}
 		jsonString string
 		answerFunc = func(resp api.GenerateResponse) error {
@@ -102,6 +124,87 @@ func (ai *AiService) GenerateGuessWords(topic string) ([]string, error) {
 	return guessWords.Words, nil
 }
 
+func (ai *AiService) GenerateQuiz(topic string, count int) ([]utils.QuizQuestion, error) {
+	if count <= 0 {
+		count = QUIZ_QUESTIONS
+	}
+
+	var (
+		ctx = context.Background()
+		req = &api.GenerateRequest{
+			Model:   SMALL_MODEL,
+			Prompt:  fmt.Sprintf(QUIZ_PROMPT, count) + topic,
+			Options: options,
+			Stream:  new(bool),
+			Format: []byte(fmt.Sprintf(`{
+				"type": "object",
+				"properties": {
+					"questions": {
+						"type": "array",
+						"minItems": %d,
+						"maxItems": %d,
+						"items": {
+							"type": "object",
+							"properties": {
+								"question": {
+									"type": "string"
+								},
+								"options": {
+									"type": "array",
+									"minItems": %d,
+									"maxItems": %d,
+									"items": {
+										"type": "string"
+									}
+								},
+								"answer": {
+									"type": "integer",
+									"minimum": 0,
+									"maximum": %d
+								}
+							},
+							"required": ["question", "options", "answer"]
+						}
+					}
+				},
+				"required": ["questions"]
+			}`, count, count, QUIZ_OPTIONS, QUIZ_OPTIONS, QUIZ_OPTIONS-1)),
+		}
+		jsonString string
+		answerFunc = func(resp api.GenerateResponse) error {
+			jsonString += resp.Response
+			return nil
+		}
+	)
+	err := ai.client.Generate(ctx, req, answerFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	var quiz struct {
+		Questions []utils.QuizQuestion `json:"questions"`
+	}
+	err = json.Unmarshal([]byte(jsonString), &quiz)
+	if err != nil {
+		return nil, err
+	}
+
+	// the model does not always stick to the schema, broken questions are left out
+	questions := []utils.QuizQuestion{}
+	for _, question := range quiz.Questions {
+		if len(question.Question) == 0 || len(question.Options) != QUIZ_OPTIONS || question.Answer < 0 || question.Answer >= QUIZ_OPTIONS {
+			continue
+		}
+		questions = append(questions, question)
+	}
+
+	if len(questions) == 0 {
+		return nil, utils.NewError("Failed to generate questions for that topic.", http.StatusInternalServerError)
+	}
+
+	return questions, nil
+}
+
 func (ai *AiService) SummarizeChat(chat utils.Chat, answerFunc api.GenerateResponseFunc) (err error) {
 	if len(chat.Messages) == 0 {
 		return utils.NewError("Not enough messages to summarize the chat.", http.StatusBadRequest)
@@ -36,6 +36,7 @@ func (c *AiHandler) RegisterRoutes(router *mux.Router) {
 	HandleFunc(router, "/rewrite", c.rewriteText)
 	HandleFunc(router, "/ask", c.askAi)
 	HandleFunc(router, "/guess", c.guessWord).Methods("POST")
+	HandleFunc(router, "/quiz", c.generateQuiz).Methods("POST")
 
 	HandleFunc(router, "/version", c.getVersion).Methods("GET")
 
@@ -55,17 +56,40 @@ func (c *AiHandler) getVersion(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param commz-token header string true "Authenticated user JWT token"
-// @Param request body TextManipulationRequest true "the topic for the words to generate"
+// @Param request body GuessWordsRequest true "the topic, number and difficulty of the words to generate"
 // @Success 200 {object} []string "generated words"
 // @Failure 400 {object} utils.ServiceError "Invalid request body or user ID"
 // @Failure 401 {object} utils.ServiceError "Unauthorized"
 // @Failure 500 {object} utils.ServiceError "Internal server error"
 // @Router /guess [post]
 func (c *AiHandler) guessWord(w http.ResponseWriter, r *http.Request) {
-	var request TextManipulationRequest
+	var request GuessWordsRequest
+	json.NewDecoder(r.Body).Decode(&request)
+
+	resp, err := c.ai.GenerateGuessWords(request.Text, request.Count, request.Difficulty)
+	if c.handleErrors(err, w) {
+		return
+	}
+	utils.SendJsonResponse(w, resp)
+}
+
+// @Summary Gives multiple-choice questions for that specific topic
+// @Description Gives multiple-choice questions with four options for that specific topic
+// @Tags ai
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param request body QuizRequest true "the topic and number of the questions to generate"
+// @Success 200 {array} utils.QuizQuestion "generated questions"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or user ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /quiz [post]
+func (c *AiHandler) generateQuiz(w http.ResponseWriter, r *http.Request) {
+	var request QuizRequest
 	json.NewDecoder(r.Body).Decode(&request)
 
-	resp, err := c.ai.GenerateGuessWords(request.Text)
+	resp, err := c.ai.GenerateQuiz(request.Text, request.Count)
 	if c.handleErrors(err, w) {
 		return
 	}
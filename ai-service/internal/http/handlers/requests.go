@@ -26,3 +26,16 @@ type TextManipulationRequest struct {
 type TextManipulationResponse struct {
 	Text string `json:"text"`
 }
+
+// GuessWordsRequest represents the request body for generating the words of a guessing game
+type GuessWordsRequest struct {
+	Text       string `json:"text"`
+	Count      int    `json:"count"`
+	Difficulty string `json:"difficulty"`
+}
+
+// QuizRequest represents the request body for generating the questions of a quiz
+type QuizRequest struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
@@ -52,3 +52,10 @@ type User struct {
 	FirstName string    `json:"first_name" bson:"first_name"`
 	LastName  string    `json:"last_name" bson:"last_name"`
 }
+
+// QuizQuestion is a multiple-choice question, Answer is the index of the correct option
+type QuizQuestion struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	Answer   int      `json:"answer"`
+}
@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nilspolek/DevOps/Chat/internal/auth"
+	"github.com/nilspolek/DevOps/Chat/internal/importer"
+	"github.com/nilspolek/DevOps/Chat/internal/storage"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	importFormat       string
+	importName         string
+	importParticipants map[string]string
+	importMembers      []string
+	importOwner        string
+	importChannels     []string
+	importTimezone     string
+	importDayFirst     bool
+)
+
+func init() {
+	importCmd.Flags().StringVar(&importFormat, "format", "", "Format of the export, whatsapp or slack. Detected from the file extension if empty")
+	importCmd.Flags().StringVar(&importName, "name", "", "Name of the chat of a WhatsApp export, defaults to the file name")
+	importCmd.Flags().StringToStringVar(&importParticipants, "participant", map[string]string{}, "Maps a name or Slack user id of the export to the email of a user, e.g. --participant \"Jane Doe=jane@example.com\"")
+	importCmd.Flags().StringSliceVar(&importMembers, "member", []string{}, "Emails of users to add to the chat in addition to the participants")
+	importCmd.Flags().StringVar(&importOwner, "owner", "", "Email of the owner of a new chat, defaults to the first participant")
+	importCmd.Flags().StringSliceVar(&importChannels, "channel", []string{}, "Names or ids of the Slack channels to import, all channels if empty")
+	importCmd.Flags().StringVar(&importTimezone, "timezone", "Local", "Time zone of the phone a WhatsApp chat was exported from")
+	importCmd.Flags().BoolVar(&importDayFirst, "day-first", true, "Read dates of a WhatsApp export day first, detected from the export if not set")
+	importCmd.Flags().String("mongo-uri", "mongodb://localhost:27017", "MongoDB URI")
+	importCmd.Flags().String("gatewayUrl", "http://localhost:4242", "Gateway URL")
+	importCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug log info")
+
+	// own keys, so the flags of start are not overridden
+	viper.BindEnv("import.mongo-uri", "MONGO_URI")
+	viper.BindPFlag("import.mongo-uri", importCmd.Flags().Lookup("mongo-uri"))
+
+	viper.BindEnv("import.gatewayUrl", "GATEWAY_URL")
+	viper.BindPFlag("import.gatewayUrl", importCmd.Flags().Lookup("gatewayUrl"))
+
+	rootCmd.AddCommand(importCmd)
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import a WhatsApp .txt export or a Slack export archive",
+	Long: `Import a WhatsApp .txt export or a Slack export archive.
+Participants are mapped to users by their email, Slack exports contain them, for WhatsApp exports they are set with --participant.
+Importing the same export again only adds the messages that were not imported before, messages of participants
+that are mapped now are moved to their users.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mongoURI = viper.GetString("import.mongo-uri")
+		gatewayUrl = viper.GetString("import.gatewayUrl")
+
+		if debug {
+			zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		} else {
+			zerolog.SetGlobalLevel(zerolog.InfoLevel)
+		}
+
+		chats, err := parseExport(cmd, args[0])
+		if err != nil {
+			logger.Fatal().Err(err).Str("file", args[0]).Msg("Failed to read export")
+			return
+		}
+
+		storage, err := storage.NewMongoDBStorage(mongoURI)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to connect to MongoDB")
+			return
+		}
+
+		authService := auth.New(gatewayUrl)
+		imp := importer.New(storage, &authService)
+
+		options := importer.Options{
+			Emails:  importParticipants,
+			Members: importMembers,
+			Owner:   importOwner,
+		}
+
+		failed := false
+		for _, chat := range chats {
+			result, err := imp.Import(chat, options)
+			if err != nil {
+				logger.Error().Err(err).Str("chat", chat.Name).Msg("Failed to import chat")
+				failed = true
+				continue
+			}
+
+			fmt.Printf("%s (%s): imported %d of %d messages\n", result.Name, result.ChatID, result.Imported, result.Messages)
+			if len(result.Unmapped) > 0 {
+				fmt.Printf("  not mapped to users: %s\n", strings.Join(result.Unmapped, ", "))
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// parseExport reads the file in the format of the flag or of its extension
+func parseExport(cmd *cobra.Command, path string) ([]importer.ImportedChat, error) {
+	format := strings.ToLower(importFormat)
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".zip":
+			format = importer.SOURCE_SLACK
+		case ".txt":
+			format = importer.SOURCE_WHATSAPP
+		default:
+			return nil, fmt.Errorf("cannot detect the format of %s, set --format", path)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch format {
+	case importer.SOURCE_SLACK:
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return importer.ParseSlack(file, info.Size(), importChannels)
+	case importer.SOURCE_WHATSAPP:
+		location, err := time.LoadLocation(importTimezone)
+		if err != nil {
+			return nil, err
+		}
+
+		options := importer.WhatsAppOptions{Location: location}
+		if cmd.Flags().Changed("day-first") {
+			options.DayFirst = &importDayFirst
+		}
+
+		name := importName
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+
+		chat, err := importer.ParseWhatsApp(file, name, options)
+		if err != nil {
+			return nil, err
+		}
+		return []importer.ImportedChat{*chat}, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %s, has to be whatsapp or slack", format)
+}
@@ -13,12 +13,16 @@ var rootCmd = &cobra.Command{
 }
 
 var (
-	port       int
-	prometheus bool
-	swagger    bool
-	debug      bool
-	mongoURI   string
-	gatewayUrl string
+	port            int
+	prometheus      bool
+	swagger         bool
+	debug           bool
+	mongoURI        string
+	gatewayUrl      string
+	maxPins         int
+	inviteSecret    string
+	unfurlLinks     bool
+	unfurlBlocklist []string
 )
 
 func Execute() {
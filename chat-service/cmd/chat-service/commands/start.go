@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	_ "github.com/nilspolek/DevOps/Chat/docs"
 	"github.com/nilspolek/DevOps/Chat/internal/ai"
@@ -10,6 +12,7 @@ import (
 	"github.com/nilspolek/DevOps/Chat/internal/chat"
 	server "github.com/nilspolek/DevOps/Chat/internal/http"
 	"github.com/nilspolek/DevOps/Chat/internal/storage"
+	"github.com/nilspolek/DevOps/Chat/internal/unfurl"
 	"github.com/nilspolek/DevOps/Chat/internal/utils"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
@@ -28,6 +31,10 @@ func init() {
 	startCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug log info")
 	startCmd.Flags().StringVar(&mongoURI, "mongo-uri", "mongodb://localhost:27017", "MongoDB URI")
 	startCmd.Flags().StringVar(&gatewayUrl, "gatewayUrl", "http://localhost:4242", "Gateway URL")
+	startCmd.Flags().IntVar(&maxPins, "max-pins", chat.DEFAULT_MAX_PINS, "Maximum number of pinned messages per chat")
+	startCmd.Flags().StringVar(&inviteSecret, "invite-secret", "", "Secret to sign invite links, required and has to be the same for all replicas")
+	startCmd.Flags().BoolVar(&unfurlLinks, "unfurl-links", true, "Add previews of links to messages")
+	startCmd.Flags().StringSliceVar(&unfurlBlocklist, "unfurl-blocklist", unfurl.DEFAULT_BLOCKLIST, "Addresses and CIDR ranges link previews are never fetched from")
 
 	viper.BindPFlag("server.port", startCmd.Flags().Lookup("port"))
 	viper.BindEnv("mongo-uri", "MONGO_URI")
@@ -36,6 +43,18 @@ func init() {
 	viper.BindEnv("gatewayUrl", "GATEWAY_URL")
 	viper.BindPFlag("gatewayUrl", startCmd.Flags().Lookup("gatewayUrl"))
 
+	viper.BindEnv("max-pins", "MAX_PINS")
+	viper.BindPFlag("max-pins", startCmd.Flags().Lookup("max-pins"))
+
+	viper.BindEnv("invite-secret", "INVITE_SECRET")
+	viper.BindPFlag("invite-secret", startCmd.Flags().Lookup("invite-secret"))
+
+	viper.BindEnv("unfurl-links", "UNFURL_LINKS")
+	viper.BindPFlag("unfurl-links", startCmd.Flags().Lookup("unfurl-links"))
+
+	viper.BindEnv("unfurl-blocklist", "UNFURL_BLOCKLIST")
+	viper.BindPFlag("unfurl-blocklist", startCmd.Flags().Lookup("unfurl-blocklist"))
+
 	rootCmd.AddCommand(startCmd)
 }
 
@@ -46,6 +65,14 @@ var startCmd = &cobra.Command{
 
 		mongoURI = viper.GetString("mongo-uri")
 		gatewayUrl = viper.GetString("gatewayUrl")
+		maxPins = viper.GetInt("max-pins")
+		inviteSecret = viper.GetString("invite-secret")
+		unfurlLinks = viper.GetBool("unfurl-links")
+		// the environment variable is a comma separated list like the flag
+		unfurlBlocklist = []string{}
+		for _, entry := range viper.GetStringSlice("unfurl-blocklist") {
+			unfurlBlocklist = append(unfurlBlocklist, strings.Split(entry, ",")...)
+		}
 
 		if debug {
 			zerolog.SetGlobalLevel(zerolog.DebugLevel)
@@ -60,11 +87,37 @@ var startCmd = &cobra.Command{
 			return
 		}
 
+		// a secret per process would break the invite links of other replicas and of earlier runs
+		if inviteSecret == "" {
+			logger.Fatal().Msg("No invite secret set, set it with --invite-secret or INVITE_SECRET")
+			return
+		}
+
 		aiService := ai.New(gatewayUrl)
 		authService := auth.New(gatewayUrl)
-		chatService := chat.New(storage, &authService, &aiService)
+		options := []chat.Option{chat.WithMaxPins(maxPins), chat.WithInviteSecret(inviteSecret)}
+
+		if unfurlLinks {
+			unfurler, err := unfurl.New(unfurlBlocklist)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("Invalid unfurl blocklist")
+				return
+			}
+			options = append(options, chat.WithUnfurler(unfurler))
+		}
+
+		chatService := chat.New(storage, &authService, &aiService, options...)
 		router := server.New(&chatService, &authService)
 
+		// deliver scheduled messages, every replica runs a dispatcher
+		go chatService.DispatchScheduledMessages(context.Background())
+		// delete messages of chats with a retention policy
+		go chatService.SweepExpiredMessages(context.Background())
+		// end guessing games whose time is up
+		go chatService.ExpireGames(context.Background())
+		// move quizzes to the next question when the time is up
+		go chatService.AdvanceQuizzes(context.Background())
+
 		// serve generated swagger documentation
 		if swagger {
 			router.Router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
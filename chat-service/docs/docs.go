@@ -22,7 +22,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves all chats (both direct and group) that the authenticated user is a member of",
+                "description": "Retrieves all chats (both direct and group) that the authenticated user is a member of, including the unread count, last message and last read time of the user.\nThe personal settings of the user are included, favourites come first followed by chats with a sort order",
                 "produces": [
                     "application/json"
                 ],
@@ -37,6 +37,19 @@ const docTemplate = `{
                         "name": "commz-token",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": true,
+                        "description": "Embed the latest messages of every chat",
+                        "name": "messages",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only list archived chats if true or chats that are not archived if false",
+                        "name": "archived",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -132,6 +145,49 @@ const docTemplate = `{
                 }
             }
         },
+        "/commands": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the slash commands that can be sent with a message, ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Get available commands",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Available commands",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.CommandInfo"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
         "/direct-chat": {
             "post": {
                 "security": [
@@ -209,24 +265,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/messages/{messageId}": {
-            "put": {
+        "/invites/{token}/join": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "returns the updated message",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Adds the authenticated user to the chat of the invite. Members of the chat do not use up the invite",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "chat"
                 ],
-                "summary": "Update a message by id",
+                "summary": "Join a chat with an invite",
                 "parameters": [
                     {
                         "type": "string",
@@ -237,33 +290,33 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Message ID",
-                        "name": "messageId",
+                        "description": "Invite token",
+                        "name": "token",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Message Updated",
+                        "description": "Joined chat",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SendMessageRequest"
+                            "$ref": "#/definitions/utils.Chat"
                         }
                     },
-                    "400": {
-                        "description": "Invalid request body or Chat ID",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Invalid invite",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
                     },
-                    "404": {
-                        "description": "Chat not found",
+                    "410": {
+                        "description": "Invite expired, revoked or used up",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -275,14 +328,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/messages/{messageId}": {
+            "put": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "returns the deleted message",
+                "description": "returns the updated message",
                 "consumes": [
                     "application/json"
                 ],
@@ -292,7 +347,7 @@ const docTemplate = `{
                 "tags": [
                     "chat"
                 ],
-                "summary": "Delete a message by id",
+                "summary": "Update a message by id",
                 "parameters": [
                     {
                         "type": "string",
@@ -311,9 +366,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Message Deleted",
+                        "description": "Message Updated",
                         "schema": {
-                            "$ref": "#/definitions/utils.Message"
+                            "$ref": "#/definitions/handlers.SendMessageRequest"
                         }
                     },
                     "400": {
@@ -341,16 +396,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/messages/{messageId}/read": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "returns the updated message",
+                "description": "returns the deleted message",
                 "consumes": [
                     "application/json"
                 ],
@@ -360,7 +413,7 @@ const docTemplate = `{
                 "tags": [
                     "chat"
                 ],
-                "summary": "Sets the status of a message to read",
+                "summary": "Delete a message by id",
                 "parameters": [
                     {
                         "type": "string",
@@ -379,9 +432,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Message Updated",
+                        "description": "Message Deleted",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SendMessageRequest"
+                            "$ref": "#/definitions/utils.Message"
                         }
                     },
                     "400": {
@@ -397,7 +450,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Chat not found or message not found",
+                        "description": "Chat not found",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -411,37 +464,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/version": {
-            "get": {
-                "summary": "Get the service Version",
-                "responses": {
-                    "200": {
-                        "description": "version",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            }
-        },
-        "/{chatId}": {
-            "get": {
+        "/messages/{messageId}/close": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns the chat with that ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Stops the voting and freezes the results, only the creator of the poll and admins can close it",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "chat"
                 ],
-                "summary": "Gets a specific chat by ID",
+                "summary": "Close a poll",
                 "parameters": [
                     {
                         "type": "string",
@@ -452,21 +489,21 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Chat ID",
-                        "name": "chatId",
+                        "description": "Message ID",
+                        "name": "messageId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Chat Found",
+                        "description": "Poll message",
                         "schema": {
-                            "$ref": "#/definitions/utils.Chat"
+                            "$ref": "#/definitions/utils.Message"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body or Chat ID",
+                        "description": "Invalid message ID or message is not a poll",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -477,8 +514,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ServiceError"
                         }
                     },
+                    "403": {
+                        "description": "Not allowed to close the poll",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
                     "404": {
-                        "description": "Chat not found",
+                        "description": "Message not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "409": {
+                        "description": "Poll already closed",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -490,14 +539,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/messages/{messageId}/forward": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Updates achat between the authenticated user other users",
+                "description": "Sends a copy of the message with its media to every target chat, the copies reference the original sender, chat and time",
                 "consumes": [
                     "application/json"
                 ],
@@ -507,7 +558,7 @@ const docTemplate = `{
                 "tags": [
                     "chat"
                 ],
-                "summary": "Updates a chat between users",
+                "summary": "Forward a message to other chats",
                 "parameters": [
                     {
                         "type": "string",
@@ -518,30 +569,33 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Chat ID",
-                        "name": "chatId",
+                        "description": "Message ID",
+                        "name": "messageId",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Direct chat creation request",
+                        "description": "Target chats",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateChatRequest"
+                            "$ref": "#/definitions/handlers.ForwardMessageRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Chat creation successful",
+                        "description": "Forwarded messages",
                         "schema": {
-                            "$ref": "#/definitions/utils.Chat"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.Message"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid request body or chat ID",
+                        "description": "Invalid request body or message ID",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -552,8 +606,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ServiceError"
                         }
                     },
+                    "403": {
+                        "description": "User not member of the source or a target chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
                     "404": {
-                        "description": "Chat not found",
+                        "description": "Message not found",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -565,24 +625,23 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/messages/{messageId}/history": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deletes an exsisting chat, the user has to be part of that chat.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "returns the current version of the message and all previous versions in chronological order",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "chat"
                 ],
-                "summary": "Delete a chat",
+                "summary": "Get the edit history of a message",
                 "parameters": [
                     {
                         "type": "string",
@@ -593,21 +652,21 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Chat ID",
-                        "name": "chatId",
+                        "description": "Message ID",
+                        "name": "messageId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Chat deleted",
+                        "description": "Message history",
                         "schema": {
-                            "type": "boolean"
+                            "$ref": "#/definitions/utils.MessageHistory"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body or chat ID",
+                        "description": "Invalid message ID",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -618,8 +677,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ServiceError"
                         }
                     },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
                     "404": {
-                        "description": "Chat not found",
+                        "description": "Message not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "410": {
+                        "description": "Message has been deleted",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -633,21 +704,24 @@ const docTemplate = `{
                 }
             }
         },
-        "/{chatId}/messages": {
-            "get": {
+        "/messages/{messageId}/reactions": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves all messages from a specific chat",
+                "description": "adds the emoji reaction of the user and returns the updated message",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "chat"
                 ],
-                "summary": "Get chat messages",
+                "summary": "React to a message with an emoji",
                 "parameters": [
                     {
                         "type": "string",
@@ -658,39 +732,30 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "format": "uuid",
-                        "description": "Chat ID",
-                        "name": "chatId",
+                        "description": "Message ID",
+                        "name": "messageId",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 50,
-                        "description": "Number of messages to return",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Number of messages to skip",
-                        "name": "offset",
-                        "in": "query"
+                        "description": "Reaction",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReactionRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "List of chat messages",
+                        "description": "Message Updated",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/utils.Message"
-                            }
+                            "$ref": "#/definitions/utils.Message"
                         }
                     },
                     "400": {
-                        "description": "Invalid chat ID",
+                        "description": "Invalid request body or message ID",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -708,7 +773,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Chat not found",
+                        "description": "Message not found",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -721,23 +786,20 @@ const docTemplate = `{
                     }
                 }
             },
-            "post": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Sends a new message to a specific chat",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "removes the emoji reaction of the user and returns the updated message",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "chat"
                 ],
-                "summary": "Send chat message",
+                "summary": "Remove a reaction from a message",
                 "parameters": [
                     {
                         "type": "string",
@@ -748,31 +810,28 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "format": "uuid",
-                        "description": "Chat ID",
-                        "name": "chatId",
+                        "description": "Message ID",
+                        "name": "messageId",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Message content",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.SendMessageRequest"
-                        }
+                        "type": "string",
+                        "description": "Emoji to remove",
+                        "name": "emoji",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Message sent successfully",
+                        "description": "Message Updated",
                         "schema": {
                             "$ref": "#/definitions/utils.Message"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body or chat ID",
+                        "description": "Invalid emoji or message ID",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -790,7 +849,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Chat not found",
+                        "description": "Message not found",
                         "schema": {
                             "$ref": "#/definitions/utils.ServiceError"
                         }
@@ -803,109 +862,3161 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.CreateChatRequest": {
-            "type": "object",
-            "properties": {
-                "members": {
-                    "description": "List of user IDs to add to the chat\nrequired: true\nminimum items: 1",
+        },
+        "/messages/{messageId}/read": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "returns the updated message",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Sets the status of a message to read",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message Updated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SendMessageRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or Chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found or message not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/{messageId}/receipts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "returns for every member except the sender if and when the message was read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Get the read receipts of a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Read receipts",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.Receipt"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid message ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Message not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/{messageId}/thread": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "returns the root message of the thread followed by all replies in chronological order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Get the thread of a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Thread messages",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.Message"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid message ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Message not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/{messageId}/vote": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Counts the vote of the user and returns the updated poll message, every member can vote once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Vote in a poll",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Vote",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.VoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Poll message",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, message ID or options",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Message not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "409": {
+                        "description": "Already voted or poll closed",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduled": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the scheduled messages of the authenticated user that have not been sent yet, including messages that failed to send",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Get scheduled messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Scheduled messages",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.ScheduledMessage"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduled/{messageId}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Changes the content or the time of a scheduled message. A message that failed to send is scheduled again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Update a scheduled message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Scheduled message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated message",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateScheduledMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated scheduled message",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ScheduledMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or message ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Scheduled message not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "409": {
+                        "description": "Message is already being sent",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a scheduled message before it is sent and returns it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Cancel a scheduled message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Scheduled message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cancelled scheduled message",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ScheduledMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid message ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Scheduled message not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "409": {
+                        "description": "Message is already being sent",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Full text search over the messages of all chats the user is a member of",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Search messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Only search in this chat",
+                        "name": "chat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Only messages of this sender",
+                        "name": "sender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Only messages sent after this time",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Only messages sent before this time",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only messages with media",
+                        "name": "has_media",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only replies",
+                        "name": "replies",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Number of results to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Search results",
+                        "schema": {
+                            "$ref": "#/definitions/utils.SearchResults"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid search query",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/version": {
+            "get": {
+                "summary": "Get the service Version",
+                "responses": {
+                    "200": {
+                        "description": "version",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the chat with that ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Gets a specific chat by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chat Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Chat"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or Chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Updates achat between the authenticated user other users, only admins can update a chat",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Updates a chat between users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Direct chat creation request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateChatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chat creation successful",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Chat"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes an exsisting chat, only the owner can delete group chats.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Delete a chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chat deleted",
+                        "schema": {
+                            "type": "boolean"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/admins/{userId}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Makes a member of the chat an admin, only the owner of the chat can promote members",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Promote a member to admin",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID of the member",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Chat"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID or user ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User is not the owner of the chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat or member not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Makes an admin of the chat a plain member again, only the owner of the chat can demote admins",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Demote an admin to member",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID of the admin",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Chat"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID or user ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User is not the owner of the chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat or member not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams the full history of the chat as JSON, Markdown or HTML with sender names, media references, reply links and deleted messages",
+                "produces": [
+                    "application/json",
+                    "text/markdown",
+                    "text/html"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Export the history of a chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json (default), markdown or html",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chat export",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID or format",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/games/leaderboard": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the all-time scores of the guessing games played in the chat, the best player first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Get the guessing game leaderboard of a chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Leaderboard",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.GameScore"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/invites": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the invites of the chat that can still be used",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Get the invites of a chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Active invites",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.Invite"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a signed invite token for a group chat with an optional expiry and usage limit, every member can create invites",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Create an invite link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Invite options",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateInviteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Created invite with its token",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Invite"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/invites/{inviteId}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Invalidates an invite, only the member who created it and admins can revoke it. Returns the remaining active invites",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Revoke an invite",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Invite ID",
+                        "name": "inviteId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Remaining active invites",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.Invite"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID or invite ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not allowed to revoke the invite",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat or invite not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "409": {
+                        "description": "Invite already revoked",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/leave": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes the authenticated user from a group chat. If the owner leaves, an admin or member becomes the new owner",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Leave a group chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chat that was left",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Chat"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/members": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Adds users to a group chat, only admins can add members",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Add members to a group chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Members to add",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AddMembersRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Chat"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User is not an admin of the chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/members/{userId}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes a member from a group chat. Admins can remove members, only the owner can remove admins",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Remove a member from a group chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID of the member",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Chat"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID or user ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User is not allowed to remove that member",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat or member not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/messages": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves all messages from a specific chat.\nIf before, after or around is set, a utils.MessagePage with the cursors of the neighbouring pages is returned instead of a list.\nSet cursor to true to get the first page, starting at the latest message, as a utils.MessagePage as well",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Get chat messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of messages to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of messages to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor or message ID, returns the messages before it",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor or message ID, returns the messages after it",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Message ID, returns the messages around it including the message itself",
+                        "name": "around",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Return the latest messages as a utils.MessagePage with cursors instead of a list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of chat messages",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.Message"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sends a new message to a specific chat. With send_at the message is scheduled and sent at that time. The kind and payload of the message are set by the server",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Send chat message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Message content",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SendMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message sent successfully, a utils.ScheduledMessage if send_at is set",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/pins": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the pinned messages of the chat, the latest pin first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Get the pinned messages of a chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Pinned messages",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.PinnedMessage"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/pins/{messageId}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Pins a message of the chat, every member can pin messages up to the configured limit per chat",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Pin a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Created pin",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Pin"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID or message ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat or message not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "409": {
+                        "description": "Message already pinned or pin limit reached",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes a pin, only the member who pinned the message and admins can remove it. Returns the remaining pins",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Unpin a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "messageId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Remaining pinned messages",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.PinnedMessage"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chat ID or message ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not allowed to remove the pin",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found or message not pinned",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/polls": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sends a poll to the chat, the question is used as the content of the message",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Send a poll",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Poll",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreatePollRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Poll message",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/read": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "returns the chat_read event that is sent to the other members",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Marks all messages of a chat up to a message or time as read",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Last seen message or time",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReadChatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chat read",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User not member of chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Message not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/retention": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sets after how many days or seconds messages of the chat are deleted, only admins can change it. Zero values keep messages forever",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Change the message retention of a chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Retention policy",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RetentionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.Chat"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "403": {
+                        "description": "User is not an admin of the chat",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "404": {
+                        "description": "Chat not found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        },
+        "/{chatId}/settings": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replaces the settings of the authenticated user for the chat. Muted chats still count unread messages but are flagged as muted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chat"
+                ],
+                "summary": "Update the personal settings of a chat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authenticated user JWT token",
+                        "name": "commz-token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chat ID",
+                        "name": "chatId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Chat settings",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ChatSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated settings",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ChatSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or chat ID",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ServiceError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.AddMembersRequest": {
+            "type": "object",
+            "properties": {
+                "members": {
+                    "description": "List of user IDs to add to the chat\nrequired: true",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.ChatSettingsRequest": {
+            "type": "object",
+            "properties": {
+                "archived": {
+                    "type": "boolean"
+                },
+                "favourite": {
+                    "type": "boolean"
+                },
+                "muted_until": {
+                    "description": "Notifications are muted until that time",
+                    "type": "string"
+                },
+                "sort_order": {
+                    "description": "Position of the chat in the chat list",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.CreateChatRequest": {
+            "type": "object",
+            "properties": {
+                "members": {
+                    "description": "List of user IDs to add to the chat\nrequired: true\nminimum items: 1",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "message": {
+                    "description": "The initial message to send\nrequired: true",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the group chat\nrequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateInviteRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "The time the invite expires, invites without expiry are valid until they are revoked",
+                    "type": "string"
+                },
+                "max_uses": {
+                    "description": "How often the invite can be used, zero allows unlimited uses",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.CreatePollRequest": {
+            "type": "object",
+            "properties": {
+                "anonymous": {
+                    "description": "Only the number of votes is shown, not who voted",
+                    "type": "boolean"
+                },
+                "closes_at": {
+                    "description": "The time the poll closes, polls without a close time are open until they are closed",
+                    "type": "string"
+                },
+                "multiple_choice": {
+                    "description": "Users can vote for several options",
+                    "type": "boolean"
+                },
+                "options": {
+                    "description": "The options to vote for\nrequired: true",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "question": {
+                    "description": "The question of the poll\nrequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ForwardMessageRequest": {
+            "type": "object",
+            "properties": {
+                "chat_ids": {
+                    "description": "The chats the message is forwarded to\nrequired: true",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.ReactionRequest": {
+            "type": "object",
+            "properties": {
+                "emoji": {
+                    "description": "The emoji to react with\nrequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ReadChatRequest": {
+            "type": "object",
+            "properties": {
+                "message_id": {
+                    "description": "The last message the user has seen",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "The time up to which all messages have been seen",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RetentionRequest": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "description": "Days after which messages are deleted",
+                    "type": "integer"
+                },
+                "message_ttl": {
+                    "description": "Seconds after which each message disappears",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.SendMessageRequest": {
+            "type": "object",
+            "properties": {
+                "command": {
+                    "type": "string"
+                },
+                "media": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "message": {
+                    "description": "The message content\nrequired: true\nmin length: 1",
+                    "type": "string"
+                },
+                "reply_to": {
+                    "type": "string"
+                },
+                "send_at": {
+                    "description": "If set, the message is scheduled and sent at that time",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.StartDirectMessageRequest": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "description": "The initial message to send\nrequired: true",
+                    "type": "string"
+                },
+                "receiver": {
+                    "description": "The ID of the user to start a chat with\nrequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UpdateScheduledMessageRequest": {
+            "type": "object",
+            "properties": {
+                "media": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "message": {
+                    "description": "The message content",
+                    "type": "string"
+                },
+                "send_at": {
+                    "description": "The time the message is sent at\nrequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.VoteRequest": {
+            "type": "object",
+            "properties": {
+                "options": {
+                    "description": "The indexes of the chosen options\nrequired: true",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "utils.AIPayload": {
+            "type": "object",
+            "properties": {
+                "complete": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "utils.Chat": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "creator_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_active": {
+                    "type": "string"
+                },
+                "last_message": {
+                    "$ref": "#/definitions/utils.Message"
+                },
+                "last_read_at": {
+                    "type": "string"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.Message"
+                    }
+                },
+                "muted": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "pins": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.Pin"
+                    }
+                },
+                "retention": {
+                    "description": "Retention limits how long messages are kept, chats without retention keep their history forever",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.Retention"
+                        }
+                    ]
+                },
+                "roles": {
+                    "description": "Roles maps member ids to their role, members without an entry are plain members",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/utils.ChatRole"
+                    }
+                },
+                "settings": {
+                    "description": "Settings are the personal settings of the requesting user, Muted tells clients to suppress notifications",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.ChatSettings"
+                        }
+                    ]
+                },
+                "unread_count": {
+                    "description": "UnreadCount, LastMessage and LastReadAt are computed for the user requesting the chat list",
+                    "type": "integer"
+                }
+            }
+        },
+        "utils.ChatRole": {
+            "type": "string",
+            "enum": [
+                "owner",
+                "admin",
+                "member"
+            ],
+            "x-enum-varnames": [
+                "ROLE_OWNER",
+                "ROLE_ADMIN",
+                "ROLE_MEMBER"
+            ]
+        },
+        "utils.ChatSettings": {
+            "type": "object",
+            "properties": {
+                "archived": {
+                    "type": "boolean"
+                },
+                "chat_id": {
+                    "type": "string"
+                },
+                "favourite": {
+                    "type": "boolean"
+                },
+                "muted_until": {
+                    "type": "string"
+                },
+                "sort_order": {
+                    "description": "SortOrder places the chat in the chat list, chats without a sort order come last",
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.CommandInfo": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "usage": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.CommandPayload": {
+            "type": "object",
+            "properties": {
+                "args": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
-                "message": {
-                    "description": "The initial message to send\nrequired: true",
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.Event": {
+            "type": "object",
+            "properties": {
+                "chat_id": {
+                    "type": "string"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "read_up_to": {
+                    "description": "ReadUpTo and Count are set for chat_read events",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.ForwardedFrom": {
+            "type": "object",
+            "properties": {
+                "chat_id": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.GameScore": {
+            "type": "object",
+            "properties": {
+                "chat_id": {
+                    "type": "string"
+                },
+                "display_name": {
+                    "type": "string"
+                },
+                "games": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "wins": {
+                    "type": "integer"
+                },
+                "words": {
+                    "description": "Words is the number of guessed words, Wins the number of games with the most guessed words",
+                    "type": "integer"
+                }
+            }
+        },
+        "utils.Invite": {
+            "type": "object",
+            "properties": {
+                "chat_id": {
+                    "type": "string"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "name": {
-                    "description": "Name of the group chat\nrequired: true",
+                "created_by": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "max_uses": {
+                    "description": "MaxUses limits how often the invite can be used, zero allows unlimited uses",
+                    "type": "integer"
+                },
+                "revoked": {
+                    "type": "boolean"
+                },
+                "token": {
+                    "description": "Token is derived from the id, it is not stored",
                     "type": "string"
+                },
+                "uses": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.SendMessageRequest": {
+        "utils.LinkPreview": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "site_name": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.Message": {
             "type": "object",
             "properties": {
+                "chat_id": {
+                    "type": "string"
+                },
                 "command": {
                     "type": "string"
                 },
+                "content": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "boolean"
+                },
+                "edit_count": {
+                    "type": "integer"
+                },
+                "edited": {
+                    "type": "boolean"
+                },
+                "edited_at": {
+                    "type": "string"
+                },
+                "forwarded_from": {
+                    "description": "ForwardedFrom is set if the message is a forwarded copy of another message",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.ForwardedFrom"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "Kind tells clients how to render the message, Payload holds the data of the kind",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.MessageKind"
+                        }
+                    ]
+                },
+                "last_reply_at": {
+                    "type": "string"
+                },
                 "media": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
+                "payload": {
+                    "$ref": "#/definitions/utils.MessagePayload"
+                },
+                "poll": {
+                    "description": "Poll is set if the message is a poll, the content holds its question",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.Poll"
+                        }
+                    ]
+                },
+                "previews": {
+                    "description": "Previews of the links in the content, they are added shortly after the message is sent",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.LinkPreview"
+                    }
+                },
+                "reactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.Reaction"
+                    }
+                },
+                "read": {
+                    "type": "boolean"
+                },
+                "read_by": {
+                    "description": "ReadBy maps the id of every member that has read the message to the time it was read.\nRead is set as soon as any member other than the sender has read the message",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "reply_count": {
+                    "description": "ReplyCount and LastReplyAt are only maintained on the root message of a thread",
+                    "type": "integer"
+                },
+                "reply_to": {
+                    "type": "string"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "thread_id": {
+                    "description": "ThreadID is the id of the root message of the thread this message is a reply in",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.MessageHistory": {
+            "type": "object",
+            "properties": {
                 "message": {
-                    "description": "The message content\nrequired: true\nmin length: 1",
+                    "$ref": "#/definitions/utils.Message"
+                },
+                "revisions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.Revision"
+                    }
+                }
+            }
+        },
+        "utils.MessageKind": {
+            "type": "string",
+            "enum": [
+                "text",
+                "media",
+                "system",
+                "command",
+                "ai",
+                "poll"
+            ],
+            "x-enum-varnames": [
+                "KIND_TEXT",
+                "KIND_MEDIA",
+                "KIND_SYSTEM",
+                "KIND_COMMAND",
+                "KIND_AI",
+                "KIND_POLL"
+            ]
+        },
+        "utils.MessagePayload": {
+            "type": "object",
+            "properties": {
+                "ai": {
+                    "$ref": "#/definitions/utils.AIPayload"
+                },
+                "command": {
+                    "$ref": "#/definitions/utils.CommandPayload"
+                },
+                "system": {
+                    "$ref": "#/definitions/utils.SystemPayload"
+                }
+            }
+        },
+        "utils.Pin": {
+            "type": "object",
+            "properties": {
+                "message_id": {
                     "type": "string"
                 },
-                "reply_to": {
+                "pinned_at": {
+                    "type": "string"
+                },
+                "pinned_by": {
                     "type": "string"
                 }
             }
         },
-        "handlers.StartDirectMessageRequest": {
+        "utils.PinnedMessage": {
             "type": "object",
             "properties": {
                 "message": {
-                    "description": "The initial message to send\nrequired: true",
+                    "$ref": "#/definitions/utils.Message"
+                },
+                "message_id": {
                     "type": "string"
                 },
-                "receiver": {
-                    "description": "The ID of the user to start a chat with\nrequired: true",
+                "pinned_at": {
+                    "type": "string"
+                },
+                "pinned_by": {
                     "type": "string"
                 }
             }
         },
-        "utils.Chat": {
+        "utils.Poll": {
             "type": "object",
             "properties": {
-                "created_at": {
+                "anonymous": {
+                    "description": "Anonymous polls only count the votes, the voters of the options are not kept",
+                    "type": "boolean"
+                },
+                "closed": {
+                    "type": "boolean"
+                },
+                "closed_at": {
                     "type": "string"
                 },
-                "creator_id": {
+                "closes_at": {
                     "type": "string"
                 },
-                "id": {
+                "multiple_choice": {
+                    "type": "boolean"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.PollOption"
+                    }
+                },
+                "question": {
                     "type": "string"
                 },
-                "last_active": {
+                "voters": {
+                    "description": "Voters is the number of users that have voted",
+                    "type": "integer"
+                }
+            }
+        },
+        "utils.PollOption": {
+            "type": "object",
+            "properties": {
+                "text": {
                     "type": "string"
                 },
-                "members": {
+                "voters": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
-                "messages": {
+                "votes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "utils.Reaction": {
+            "type": "object",
+            "properties": {
+                "emoji": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.Receipt": {
+            "type": "object",
+            "properties": {
+                "read": {
+                    "type": "boolean"
+                },
+                "read_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.Retention": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "description": "Days after which messages are deleted",
+                    "type": "integer"
+                },
+                "message_ttl": {
+                    "description": "MessageTTL makes messages disappear this many seconds after they were sent",
+                    "type": "integer"
+                }
+            }
+        },
+        "utils.Revision": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "edited_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "media": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/utils.Message"
+                        "type": "string"
                     }
                 },
-                "name": {
+                "message_id": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "Timestamp is the time this version was written, EditedAt the time it was replaced",
                     "type": "string"
                 }
             }
         },
-        "utils.Message": {
+        "utils.ScheduledMessage": {
             "type": "object",
             "properties": {
                 "chat_id": {
                     "type": "string"
                 },
-                "command": {
+                "content": {
                     "type": "string"
                 },
-                "content": {
+                "created_at": {
                     "type": "string"
                 },
-                "deleted": {
-                    "type": "boolean"
+                "error": {
+                    "type": "string"
                 },
                 "id": {
                     "type": "string"
@@ -916,23 +4027,71 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "read": {
-                    "type": "boolean"
-                },
                 "reply_to": {
                     "type": "string"
                 },
-                "sender": {
+                "send_at": {
                     "type": "string"
                 },
-                "timestamp": {
+                "sender_id": {
                     "type": "string"
                 },
-                "updatedAt": {
+                "status": {
+                    "$ref": "#/definitions/utils.ScheduledStatus"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.ScheduledStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "sending",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "SCHEDULED_PENDING",
+                "SCHEDULED_SENDING",
+                "SCHEDULED_FAILED"
+            ]
+        },
+        "utils.SearchResult": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "$ref": "#/definitions/utils.Message"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "snippet": {
+                    "description": "Snippet is the part of the message around the first match with all matches highlighted",
                     "type": "string"
                 }
             }
         },
+        "utils.SearchResults": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.SearchResult"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "utils.ServiceError": {
             "type": "object",
             "properties": {
@@ -946,6 +4105,54 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "utils.SystemEvent": {
+            "type": "string",
+            "enum": [
+                "command_reply",
+                "member_joined",
+                "members_added",
+                "member_removed",
+                "member_left",
+                "message_pinned",
+                "retention_changed"
+            ],
+            "x-enum-varnames": [
+                "EVENT_COMMAND_REPLY",
+                "EVENT_MEMBER_JOINED",
+                "EVENT_MEMBERS_ADDED",
+                "EVENT_MEMBER_REMOVED",
+                "EVENT_MEMBER_LEFT",
+                "EVENT_MESSAGE_PINNED",
+                "EVENT_RETENTION_CHANGED"
+            ]
+        },
+        "utils.SystemPayload": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Actor is the user that caused the event",
+                    "type": "string"
+                },
+                "command": {
+                    "description": "Command is set on replies of a command",
+                    "type": "string"
+                },
+                "event": {
+                    "$ref": "#/definitions/utils.SystemEvent"
+                },
+                "message_id": {
+                    "description": "MessageID is the message the event is about, e.g. the pinned message",
+                    "type": "string"
+                },
+                "users": {
+                    "description": "Users are the users the event is about, e.g. the added members or the new owner",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
         }
     }
 }`
@@ -23,9 +23,22 @@ func (ai *AiService) AskAI(prompt string, response func(response utils.GenerateR
 	}, response)
 }
 
-func (ai *AiService) GuessWords(topic string) ([]string, error) {
-	result, err := utils.PostRequest[TextManipulationRequest, []string](ai.gateway+"/ai/guess", TextManipulationRequest{
-		Text: topic,
+func (ai *AiService) GuessWords(topic string, count int, difficulty string) ([]string, error) {
+	result, err := utils.PostRequest[GuessWordsRequest, []string](ai.gateway+"/ai/guess", GuessWordsRequest{
+		Text:       topic,
+		Count:      count,
+		Difficulty: difficulty,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return *result, nil
+}
+
+func (ai *AiService) GenerateQuiz(topic string, count int) ([]utils.QuizQuestion, error) {
+	result, err := utils.PostRequest[QuizRequest, []utils.QuizQuestion](ai.gateway+"/ai/quiz", QuizRequest{
+		Text:  topic,
+		Count: count,
 	})
 	if err != nil {
 		return nil, err
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -75,3 +77,43 @@ func (a *AuthService) Exists(ids ...uuid.UUID) (bool, error) {
 	}
 	return true, nil
 }
+
+// GetUsers returns the users with the given ids, unknown ids are left out
+func (a *AuthService) GetUsers(ids ...uuid.UUID) (map[uuid.UUID]utils.User, error) {
+	users, err := utils.GetRequest[[]utils.User](a.gateway + "/auth/users")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID]utils.User, len(ids))
+	for _, user := range *users {
+		if slices.Contains(ids, user.ID) {
+			user.Password = ""
+			result[user.ID] = user
+		}
+	}
+	return result, nil
+}
+
+// GetUsersByEmail returns the users with the given emails by their lower case email, unknown emails are left out
+func (a *AuthService) GetUsersByEmail(emails ...string) (map[string]utils.User, error) {
+	users, err := utils.GetRequest[[]utils.User](a.gateway + "/auth/users")
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		wanted[strings.ToLower(strings.TrimSpace(email))] = true
+	}
+
+	result := make(map[string]utils.User, len(emails))
+	for _, user := range *users {
+		email := strings.ToLower(user.Email)
+		if wanted[email] {
+			user.Password = ""
+			result[email] = user
+		}
+	}
+	return result, nil
+}
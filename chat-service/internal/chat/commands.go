@@ -0,0 +1,194 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+// Command is a slash command users can send to a chat
+type Command interface {
+	// Name is used to invoke the command, without the leading slash
+	Name() string
+	// Usage shows the arguments of the command, e.g. "/guess [topic|word]"
+	Usage() string
+	Description() string
+	// ParseArgs validates the arguments before the command message is sent
+	ParseArgs(args string) ([]string, error)
+	// Run executes the command after the command message was sent
+	Run(ctx CommandContext) error
+}
+
+// CommandContext describes a single invocation of a command
+type CommandContext struct {
+	UserID uuid.UUID
+	ChatID uuid.UUID
+	// Name is the command without the leading slash
+	Name string
+	// Args are the parsed arguments, Raw is the text the user sent
+	Args []string
+	Raw  string
+
+	chat *ChatService
+}
+
+// Reply posts a message of the bot to the chat the command was sent to
+func (ctx CommandContext) Reply(content string) error {
+	return ctx.chat.postSystemMessage(ctx.ChatID, content, utils.SystemPayload{
+		Event:   utils.EVENT_COMMAND_REPLY,
+		Actor:   &ctx.UserID,
+		Command: ctx.Name,
+	})
+}
+
+// WithCommands registers additional commands, built-in commands can not be replaced
+func WithCommands(commands ...Command) Option {
+	return func(c *ChatService) {
+		for _, command := range commands {
+			err := c.RegisterCommand(command)
+			if err != nil {
+				logger.Err(err).Str("command", command.Name()).Msg("failed to register command")
+			}
+		}
+	}
+}
+
+// RegisterCommand adds a command to the chat service
+func (c *ChatService) RegisterCommand(command Command) error {
+	name := strings.ToLower(command.Name())
+	if len(name) == 0 || strings.ContainsAny(name, " /") {
+		return fmt.Errorf("invalid command name %q", command.Name())
+	}
+
+	if _, exists := c.commands[name]; exists {
+		return fmt.Errorf("command %q is already registered", name)
+	}
+
+	c.commands[name] = command
+	return nil
+}
+
+// GetCommands returns the registered commands ordered by name
+func (c *ChatService) GetCommands() []utils.CommandInfo {
+	commands := make([]utils.CommandInfo, 0, len(c.commands))
+	for _, command := range c.commands {
+		commands = append(commands, utils.CommandInfo{
+			Name:        strings.ToLower(command.Name()),
+			Usage:       command.Usage(),
+			Description: command.Description(),
+		})
+	}
+
+	slices.SortFunc(commands, func(a, b utils.CommandInfo) int { return strings.Compare(a.Name, b.Name) })
+	return commands
+}
+
+// Command sends the command message to the chat and runs the command in the background
+func (c *ChatService) Command(userId uuid.UUID, chatId uuid.UUID, content, command string) (*utils.Message, error) {
+	// handle ai chat
+	if chatId.String() == userId.String() {
+		return nil, utils.NewError("command not supported for AI chat", http.StatusBadRequest)
+	}
+
+	name := strings.ToLower(strings.TrimPrefix(command, "/"))
+	handler, exists := c.commands[name]
+	if !exists {
+		return nil, utils.NewError("command not supported", http.StatusBadRequest)
+	}
+
+	args, err := handler.ParseArgs(content)
+	if err != nil {
+		return nil, utils.NewError(fmt.Sprintf("%s, usage: %s", err.Error(), handler.Usage()), http.StatusBadRequest)
+	}
+
+	// check if the user is part of that chat
+	member := c.MemberOfChat(userId, chatId)
+	if !member {
+		return nil, utils.NewError("User is not a member of the chat", http.StatusUnauthorized)
+	}
+
+	message := utils.Message{
+		ID:        uuid.New(),
+		ChatID:    chatId,
+		SenderID:  userId,
+		Timestamp: time.Now(),
+		UpdatedAt: time.Now(),
+		Content:   content,
+		Command:   name,
+		Kind:      utils.KIND_COMMAND,
+		Payload:   &utils.MessagePayload{Command: &utils.CommandPayload{Name: name, Args: args}},
+	}
+
+	err = c.validateMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.storage.SaveMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := CommandContext{
+		UserID: userId,
+		ChatID: chatId,
+		Name:   name,
+		Args:   args,
+		Raw:    content,
+		chat:   c,
+	}
+
+	go func() {
+		err := handler.Run(ctx)
+		if err != nil {
+			logger.Err(err).Str("command", name).Msg("command failed")
+			ctx.Reply(fmt.Sprintf("The command `/%s` failed. Please try again.", name))
+		}
+	}()
+
+	err = c.storage.UpdateChatActivity(chatId)
+	return &message, err
+}
+
+// helpCommand lists the available commands or explains a single command
+type helpCommand struct{}
+
+func (helpCommand) Name() string { return "help" }
+
+func (helpCommand) Usage() string { return "/help [command]" }
+
+func (helpCommand) Description() string { return "Shows the available commands" }
+
+func (helpCommand) ParseArgs(args string) ([]string, error) {
+	fields := strings.Fields(args)
+	if len(fields) > 1 {
+		return nil, fmt.Errorf("help takes at most one command")
+	}
+	return fields, nil
+}
+
+func (helpCommand) Run(ctx CommandContext) error {
+	commands := ctx.chat.GetCommands()
+
+	if len(ctx.Args) == 1 {
+		name := strings.ToLower(strings.TrimPrefix(ctx.Args[0], "/"))
+		index := slices.IndexFunc(commands, func(command utils.CommandInfo) bool { return command.Name == name })
+		if index < 0 {
+			return ctx.Reply(fmt.Sprintf("There is no command `/%s`. Use `/help` to see all commands.", name))
+		}
+		commands = commands[index : index+1]
+	}
+
+	var help strings.Builder
+	help.WriteString("# Commands\n\n")
+	for _, command := range commands {
+		help.WriteString(fmt.Sprintf("`%s` - %s\n\n", command.Usage, command.Description))
+	}
+
+	return ctx.Reply(help.String())
+}
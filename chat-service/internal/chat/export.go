@@ -0,0 +1,277 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+const (
+	EXPORT_JSON     = "json"
+	EXPORT_MARKDOWN = "markdown"
+	EXPORT_HTML     = "html"
+	// the history is loaded and written in pages, so large chats are not held in memory
+	EXPORT_PAGE_SIZE = 200
+	SYSTEM_NAME      = "Commz"
+)
+
+// ChatExport writes the full history of a chat in one of the export formats
+type ChatExport struct {
+	Chat   utils.Chat
+	Format string
+
+	chat  *ChatService
+	names map[uuid.UUID]string
+}
+
+// ExportedMessage is a message of a JSON export with the resolved name of the sender
+type ExportedMessage struct {
+	utils.Message
+	SenderName string `json:"sender_name"`
+}
+
+// ExportChat checks that the user may export the chat, the history is only loaded when the export is written
+func (c *ChatService) ExportChat(userId uuid.UUID, chatId uuid.UUID, format string) (*ChatExport, error) {
+	format = strings.ToLower(format)
+	if format == "" {
+		format = EXPORT_JSON
+	}
+
+	if format != EXPORT_JSON && format != EXPORT_MARKDOWN && format != EXPORT_HTML {
+		return nil, utils.NewError("format has to be json, markdown or html", http.StatusBadRequest)
+	}
+
+	if !c.MemberOfChat(userId, chatId) {
+		return nil, utils.NewError("User is not a member of the chat", http.StatusUnauthorized)
+	}
+
+	chat, err := c.storage.GetChat(chatId)
+	if err != nil {
+		return nil, utils.NewError("chat not found", http.StatusNotFound)
+	}
+
+	return &ChatExport{
+		Chat:   *chat,
+		Format: format,
+		chat:   c,
+		names:  map[uuid.UUID]string{uuid.MustParse(utils.AIChat): SYSTEM_NAME},
+	}, nil
+}
+
+func (e *ChatExport) ContentType() string {
+	switch e.Format {
+	case EXPORT_MARKDOWN:
+		return "text/markdown; charset=utf-8"
+	case EXPORT_HTML:
+		return "text/html; charset=utf-8"
+	}
+	return "application/json"
+}
+
+func (e *ChatExport) FileName() string {
+	extension := map[string]string{EXPORT_JSON: "json", EXPORT_MARKDOWN: "md", EXPORT_HTML: "html"}[e.Format]
+	return fmt.Sprintf("chat-%s.%s", e.Chat.ID, extension)
+}
+
+// Write streams the history page by page, the oldest message first
+func (e *ChatExport) Write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+
+	err := e.writeHeader(out)
+	if err != nil {
+		return err
+	}
+
+	cursor := utils.MessageCursor{}
+	first := true
+	for {
+		messages, err := e.chat.storage.GetChatMessagesFrom(e.Chat.ID, cursor, false, false, EXPORT_PAGE_SIZE)
+		if err != nil {
+			return err
+		}
+
+		e.resolveNames(messages)
+		for _, message := range messages {
+			err = e.writeMessage(out, message, first)
+			if err != nil {
+				return err
+			}
+			first = false
+		}
+
+		// send every page right away instead of buffering the whole export
+		err = out.Flush()
+		if err != nil {
+			return err
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		if len(messages) < EXPORT_PAGE_SIZE {
+			break
+		}
+
+		last := messages[len(messages)-1]
+		cursor = utils.MessageCursor{Timestamp: last.Timestamp, ID: last.ID}
+	}
+
+	err = e.writeFooter(out)
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// resolveNames looks up the senders of a page that are not known yet
+func (e *ChatExport) resolveNames(messages []utils.Message) {
+	unknown := []uuid.UUID{}
+	for _, message := range messages {
+		if _, ok := e.names[message.SenderID]; !ok {
+			e.names[message.SenderID] = ""
+			unknown = append(unknown, message.SenderID)
+		}
+	}
+
+	for id, name := range e.chat.displayNames(unknown) {
+		e.names[id] = name
+	}
+}
+
+func (e *ChatExport) writeHeader(w io.Writer) error {
+	exportedAt := time.Now().UTC()
+
+	switch e.Format {
+	case EXPORT_MARKDOWN:
+		_, err := fmt.Fprintf(w, "# %s\n\nExported at %s\n\n---\n\n", e.Chat.Name, exportedAt.Format(time.RFC3339))
+		return err
+	case EXPORT_HTML:
+		_, err := fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%[1]s</title>\n</head>\n<body>\n<h1>%[1]s</h1>\n<p>Exported at %[2]s</p>\n",
+			html.EscapeString(e.Chat.Name), exportedAt.Format(time.RFC3339))
+		return err
+	}
+
+	chat, err := json.Marshal(e.Chat)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "{\"chat\":%s,\"exported_at\":%q,\"messages\":[", chat, exportedAt.Format(time.RFC3339))
+	return err
+}
+
+func (e *ChatExport) writeFooter(w io.Writer) error {
+	var err error
+	switch e.Format {
+	case EXPORT_MARKDOWN:
+	case EXPORT_HTML:
+		_, err = io.WriteString(w, "</body>\n</html>\n")
+	default:
+		_, err = io.WriteString(w, "]}\n")
+	}
+	return err
+}
+
+func (e *ChatExport) writeMessage(w io.Writer, message utils.Message, first bool) error {
+	switch e.Format {
+	case EXPORT_MARKDOWN:
+		return e.writeMarkdown(w, message)
+	case EXPORT_HTML:
+		return e.writeHTML(w, message)
+	}
+
+	if !first {
+		_, err := io.WriteString(w, ",")
+		if err != nil {
+			return err
+		}
+	}
+
+	return json.NewEncoder(w).Encode(ExportedMessage{Message: message, SenderName: e.names[message.SenderID]})
+}
+
+func (e *ChatExport) writeMarkdown(w io.Writer, message utils.Message) error {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("<a id=\"%s\"></a>\n**%s** · %s\n\n", messageAnchor(message.ID), e.names[message.SenderID], message.Timestamp.UTC().Format(time.RFC3339)))
+
+	if message.ReplyTo != nil {
+		text.WriteString(fmt.Sprintf("> Reply to [this message](#%s)\n\n", messageAnchor(*message.ReplyTo)))
+	}
+
+	if message.ForwardedFrom != nil {
+		text.WriteString(fmt.Sprintf("> Forwarded from %s\n\n", e.forwardedName(message.ForwardedFrom)))
+	}
+
+	if message.Deleted {
+		text.WriteString("_This message was deleted._\n\n")
+	} else {
+		if len(message.Content) > 0 {
+			text.WriteString(message.Content + "\n\n")
+		}
+		for _, media := range message.Media {
+			text.WriteString(fmt.Sprintf("- Attachment: [%[1]s](/media/%[1]s)\n", media))
+		}
+		if len(message.Media) > 0 {
+			text.WriteString("\n")
+		}
+		if message.Edited {
+			text.WriteString("_edited_\n\n")
+		}
+	}
+
+	text.WriteString("---\n\n")
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+func (e *ChatExport) writeHTML(w io.Writer, message utils.Message) error {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("<div class=\"message\" id=\"%s\">\n<p><strong>%s</strong> <time datetime=\"%[3]s\">%[3]s</time></p>\n",
+		messageAnchor(message.ID), html.EscapeString(e.names[message.SenderID]), message.Timestamp.UTC().Format(time.RFC3339)))
+
+	if message.ReplyTo != nil {
+		text.WriteString(fmt.Sprintf("<p class=\"reply\">Reply to <a href=\"#%s\">this message</a></p>\n", messageAnchor(*message.ReplyTo)))
+	}
+
+	if message.ForwardedFrom != nil {
+		text.WriteString(fmt.Sprintf("<p class=\"forwarded\">Forwarded from %s</p>\n", html.EscapeString(e.forwardedName(message.ForwardedFrom))))
+	}
+
+	if message.Deleted {
+		text.WriteString("<p class=\"deleted\"><em>This message was deleted.</em></p>\n")
+	} else {
+		if len(message.Content) > 0 {
+			content := strings.ReplaceAll(html.EscapeString(message.Content), "\n", "<br>\n")
+			text.WriteString(fmt.Sprintf("<p>%s</p>\n", content))
+		}
+		for _, media := range message.Media {
+			text.WriteString(fmt.Sprintf("<p class=\"attachment\"><a href=\"/media/%[1]s\">%[1]s</a></p>\n", media))
+		}
+		if message.Edited {
+			text.WriteString("<p class=\"edited\"><em>edited</em></p>\n")
+		}
+	}
+
+	text.WriteString("</div>\n")
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// forwardedName names the original sender, who may not be part of this chat
+func (e *ChatExport) forwardedName(from *utils.ForwardedFrom) string {
+	if _, ok := e.names[from.SenderID]; !ok {
+		e.resolveNames([]utils.Message{{SenderID: from.SenderID}})
+	}
+	return e.names[from.SenderID]
+}
+
+func messageAnchor(id uuid.UUID) string {
+	return "msg-" + id.String()
+}
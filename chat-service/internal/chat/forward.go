@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+const (
+	MAX_FORWARD_TARGETS = 20
+)
+
+// ForwardMessage sends a copy of the message with its media to every target chat.
+// The user has to be a member of the source chat and of every target chat
+func (c *ChatService) ForwardMessage(userId uuid.UUID, messageId uuid.UUID, chatIds []uuid.UUID) ([]utils.Message, error) {
+	message, err := c.storage.GetMessage(messageId)
+	if err != nil {
+		return nil, utils.NewError("message not found", http.StatusNotFound)
+	}
+
+	err = c.storage.MemberOfChat(userId, message.ChatID)
+	if err != nil {
+		return nil, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	if message.Deleted {
+		return nil, utils.NewError("cannot forward a deleted message", http.StatusBadRequest)
+	}
+
+	// the votes belong to the original poll
+	if message.Poll != nil {
+		return nil, utils.NewError("cannot forward a poll", http.StatusBadRequest)
+	}
+
+	targets := []uuid.UUID{}
+	for _, chatId := range chatIds {
+		if !slices.Contains(targets, chatId) {
+			targets = append(targets, chatId)
+		}
+	}
+
+	if len(targets) == 0 || len(targets) > MAX_FORWARD_TARGETS {
+		return nil, utils.NewError(fmt.Sprintf("a message can be forwarded to between 1 and %d chats", MAX_FORWARD_TARGETS), http.StatusBadRequest)
+	}
+
+	// check every target first, so the message is either forwarded to all chats or to none
+	for _, chatId := range targets {
+		if chatId == userId {
+			return nil, utils.NewError("cannot forward to the AI chat", http.StatusBadRequest)
+		}
+
+		err = c.storage.MemberOfChat(userId, chatId)
+		if err != nil {
+			return nil, utils.NewError(fmt.Sprintf("not a member of chat %s", chatId), http.StatusForbidden)
+		}
+	}
+
+	// forwarding a forwarded message keeps the reference to the original
+	forwardedFrom := message.ForwardedFrom
+	if forwardedFrom == nil {
+		forwardedFrom = &utils.ForwardedFrom{
+			MessageID: message.ID,
+			SenderID:  message.SenderID,
+			ChatID:    message.ChatID,
+			Timestamp: message.Timestamp,
+		}
+	}
+
+	forwarded := make([]utils.Message, 0, len(targets))
+	for _, chatId := range targets {
+		sent, err := c.sendMessage(uuid.New(), userId, chatId, message.Content, message.Media, nil, forwardedFrom)
+		if err != nil {
+			return nil, err
+		}
+		forwarded = append(forwarded, *sent)
+	}
+
+	return forwarded, nil
+}
@@ -0,0 +1,500 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+const (
+	GUESSING_GAME_DURATION = 2 * time.Minute
+	MIN_GAME_DURATION      = 30 * time.Second
+	MAX_GAME_DURATION      = 10 * time.Minute
+	DEFAULT_GAME_WORDS     = 8
+	MIN_GAME_WORDS         = 3
+	MAX_GAME_WORDS         = 20
+	DEFAULT_DIFFICULTY     = "medium"
+	// a game that is still waiting for its words after this time is given up
+	GAME_START_TIMEOUT   = 1 * time.Minute
+	GAME_EXPIRY_INTERVAL = 1 * time.Second
+	LEADERBOARD_SIZE     = 10
+	// the AI is asked again for the words that were dropped as duplicates
+	GAME_WORD_ATTEMPTS = 3
+)
+
+var difficulties = []string{"easy", "medium", "hard"}
+
+// guessCommand starts a guessing game about a topic, while a game is running it guesses a word
+type guessCommand struct{}
+
+func (guessCommand) Name() string { return "guess" }
+
+func (guessCommand) Usage() string {
+	return "/guess [topic] [duration=2m] [words=8] [difficulty=easy|medium|hard] [hints=on], /guess [word] or /guess hint"
+}
+
+func (guessCommand) Description() string {
+	return "Starts a guessing game about a topic, while a game is running guesses one of the words or asks for a hint"
+}
+
+func (guessCommand) ParseArgs(args string) ([]string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("a topic or a word is required")
+	}
+
+	// options are only parsed when a game is started, guesses may contain anything
+	return fields, nil
+}
+
+func (guessCommand) Run(ctx CommandContext) error {
+	game, err := ctx.chat.storage.GetActiveGame(ctx.ChatID)
+	if err != nil {
+		return err
+	}
+
+	hint := len(ctx.Args) == 1 && strings.EqualFold(ctx.Args[0], "hint")
+
+	if game == nil {
+		if hint {
+			return ctx.Reply("There is no game running. Use `/guess [topic]` to start one.")
+		}
+
+		options, topic, err := parseGameOptions(ctx.Args)
+		if err != nil {
+			return ctx.Reply(fmt.Sprintf("%s, usage: %s", err.Error(), guessCommand{}.Usage()))
+		}
+		return ctx.chat.startGuessingGame(ctx, topic, options)
+	}
+
+	if hint {
+		return ctx.chat.revealHint(ctx, game)
+	}
+	return ctx.chat.guessWord(ctx, game, strings.Join(ctx.Args, " "))
+}
+
+// parseGameOptions splits the arguments into the key=value options and the topic
+func parseGameOptions(args []string) (utils.GameOptions, string, error) {
+	options := utils.GameOptions{
+		Duration:   int(GUESSING_GAME_DURATION.Seconds()),
+		Words:      DEFAULT_GAME_WORDS,
+		Difficulty: DEFAULT_DIFFICULTY,
+	}
+
+	topic := []string{}
+	for _, arg := range args {
+		key, value, found := strings.Cut(arg, "=")
+		if !found {
+			topic = append(topic, arg)
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "duration":
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration < MIN_GAME_DURATION || duration > MAX_GAME_DURATION {
+				return options, "", fmt.Errorf("duration has to be between %s and %s", MIN_GAME_DURATION, MAX_GAME_DURATION)
+			}
+			options.Duration = int(duration.Seconds())
+		case "words":
+			words, err := strconv.Atoi(value)
+			if err != nil || words < MIN_GAME_WORDS || words > MAX_GAME_WORDS {
+				return options, "", fmt.Errorf("words has to be between %d and %d", MIN_GAME_WORDS, MAX_GAME_WORDS)
+			}
+			options.Words = words
+		case "difficulty":
+			difficulty := strings.ToLower(value)
+			if !slices.Contains(difficulties, difficulty) {
+				return options, "", fmt.Errorf("difficulty has to be one of %s", strings.Join(difficulties, ", "))
+			}
+			options.Difficulty = difficulty
+		case "hints":
+			switch strings.ToLower(value) {
+			case "on", "true", "yes":
+				options.Hints = true
+			case "off", "false", "no":
+				options.Hints = false
+			default:
+				return options, "", fmt.Errorf("hints has to be on or off")
+			}
+		default:
+			return options, "", fmt.Errorf("unknown option %q", key)
+		}
+	}
+
+	return options, strings.Join(topic, " "), nil
+}
+
+func (c *ChatService) startGuessingGame(ctx CommandContext, topic string, options utils.GameOptions) error {
+	if len(topic) == 0 {
+		return ctx.Reply("Please name a topic to start a game.")
+	}
+
+	now := time.Now()
+	game := utils.Game{
+		ID:        uuid.New(),
+		ChatID:    ctx.ChatID,
+		Topic:     topic,
+		Words:     []string{},
+		Guesses:   []utils.GameGuess{},
+		Status:    utils.GAME_STARTING,
+		StartedBy: ctx.UserID,
+		StartedAt: now,
+		Deadline:  now.Add(GAME_START_TIMEOUT),
+		Options:   options,
+		Hints:     []int{},
+		Active:    true,
+	}
+
+	// another replica may have started a game at the same time
+	started, err := c.storage.StartGame(game)
+	if err != nil {
+		return err
+	}
+
+	if !started {
+		return ctx.Reply("A game is already starting. Please wait a moment...")
+	}
+
+	err = ctx.Reply("Starting a new game. Please wait a moment...")
+	if err != nil {
+		return err
+	}
+
+	// create a new guessing game
+	words, err := c.gameWords(topic, options)
+	if err != nil || len(words) == 0 {
+		_, err = c.storage.FinishGame(game.ID, utils.GAME_FAILED, time.Now())
+		if err != nil {
+			return err
+		}
+		return ctx.Reply("Failed to generate guesses for that topic. Please try again.")
+	}
+
+	// the game ends after some time if not all words have been guessed
+	duration := time.Duration(options.Duration) * time.Second
+	running, err := c.storage.SetGameWords(game.ID, words, time.Now().Add(duration))
+	if err != nil {
+		return err
+	}
+
+	// the game was given up while the words were generated
+	if !running {
+		return nil
+	}
+
+	usage := "Use: `/guess [word]` - to guess the words."
+	if options.Hints {
+		usage += "\n\nUse: `/guess hint` - to reveal a letter."
+	}
+
+	// send a message that a new game has started
+	return ctx.Reply(fmt.Sprintf("# A new guessing game has started about **%s**. \nThere are **%v** words to guess in **%s**.\n\n%s\n\nGood luck guessing!", topic, len(words), duration, usage))
+}
+
+// gameWords asks the AI for the words of a game. Every word can only be guessed once,
+// so duplicates are dropped and the AI is asked again for the missing words
+func (c *ChatService) gameWords(topic string, options utils.GameOptions) ([]string, error) {
+	words := []string{}
+	seen := map[string]bool{}
+
+	for attempt := 0; attempt < GAME_WORD_ATTEMPTS && len(words) < options.Words; attempt++ {
+		generated, err := c.ai.GuessWords(topic, options.Words-len(words), options.Difficulty)
+		if err != nil {
+			return nil, err
+		}
+
+		dropped := false
+		for _, word := range generated {
+			word = strings.TrimSpace(word)
+			key := strings.ToLower(word)
+			if word == "" || seen[key] {
+				dropped = true
+				continue
+			}
+			seen[key] = true
+			words = append(words, word)
+		}
+
+		// the model does not always stick to the requested number of words
+		if !dropped {
+			break
+		}
+	}
+
+	if len(words) > options.Words {
+		words = words[:options.Words]
+	}
+	return words, nil
+}
+
+// revealHint reveals the next letter of the first word that has not been guessed yet
+func (c *ChatService) revealHint(ctx CommandContext, game *utils.Game) error {
+	if !game.Options.Hints {
+		return ctx.Reply("Hints are disabled for this game.")
+	}
+
+	if game.Status == utils.GAME_STARTING {
+		return ctx.Reply("The game is still starting. Please wait a moment...")
+	}
+
+	for i, word := range game.Words {
+		guessed := slices.ContainsFunc(game.Guesses, func(g utils.GameGuess) bool { return g.Word == word })
+		// the last letter is never revealed
+		if guessed || hintLetters(game, i) >= len([]rune(word))-1 {
+			continue
+		}
+
+		updated, err := c.storage.AddGameHint(game.ID, i)
+		if err != nil {
+			return err
+		}
+
+		if updated == nil {
+			return ctx.Reply("The game is already over.")
+		}
+
+		return ctx.Reply(fmt.Sprintf("Hint: `%s`", maskWord(word, hintLetters(updated, i))))
+	}
+
+	return ctx.Reply("There are no more hints for this game.")
+}
+
+func hintLetters(game *utils.Game, index int) int {
+	if index < len(game.Hints) {
+		return game.Hints[index]
+	}
+	return 0
+}
+
+// maskWord hides all letters of the word except for the first revealed ones
+func maskWord(word string, revealed int) string {
+	masked := []string{}
+	letters := 0
+	for _, r := range word {
+		switch {
+		case r == ' ':
+			masked = append(masked, " ")
+		case letters < revealed:
+			masked = append(masked, string(r))
+			letters++
+		default:
+			masked = append(masked, "_")
+			letters++
+		}
+	}
+	return strings.Join(masked, " ")
+}
+
+func (c *ChatService) guessWord(ctx CommandContext, game *utils.Game, content string) error {
+	if game.Status == utils.GAME_STARTING {
+		return ctx.Reply("The game is still starting. Please wait a moment...")
+	}
+
+	if !time.Now().Before(game.Deadline) {
+		return ctx.Reply("Time is up! The game is about to end.")
+	}
+
+	// check if the guessed word is one of the words
+	index := slices.IndexFunc(game.Words, func(word string) bool { return strings.EqualFold(word, content) })
+	if index < 0 {
+		// send the message that the user has guessed the wrong word
+		return ctx.Reply("Sorry, that is not a correct word. Please try again.")
+	}
+
+	// the guess is only counted if no one guessed the word before
+	updated, err := c.storage.AddGameGuess(game.ID, utils.GameGuess{
+		Word:      game.Words[index],
+		UserID:    ctx.UserID,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if updated == nil {
+		return ctx.Reply("That word has already been guessed. Please try another one.")
+	}
+
+	// check if all words have been guessed
+	if len(updated.Guesses) < len(updated.Words) {
+		// send the message that the user has guessed the word
+		return ctx.Reply(fmt.Sprintf("Congratulations, you have guessed one of the words. \n\nThere are still **%d** words left.", len(updated.Words)-len(updated.Guesses)))
+	}
+
+	finished, err := c.storage.FinishGame(updated.ID, utils.GAME_WON, time.Now())
+	if err != nil || !finished {
+		return err
+	}
+
+	err = c.recordScores(updated)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Reply(c.leaderboard(updated, "🏆 Game Over! Final Leaderboard:\n\n") + "\nCongratulations, all words have been guessed!")
+}
+
+// ExpireGames ends guessing games whose deadline has passed until the context is cancelled.
+// Every game is claimed before it is announced, so several replicas can run at the same time
+func (c *ChatService) ExpireGames(ctx context.Context) {
+	ticker := time.NewTicker(GAME_EXPIRY_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.expireGames(time.Now())
+		}
+	}
+}
+
+func (c *ChatService) expireGames(now time.Time) {
+	for {
+		game, err := c.storage.ClaimExpiredGame(now)
+		if err != nil {
+			logger.Err(err).Msg("error while claiming expired games")
+			return
+		}
+
+		if game == nil {
+			return
+		}
+
+		// games that never got their words were not announced
+		if len(game.Words) == 0 {
+			continue
+		}
+
+		err = c.recordScores(game)
+		if err != nil {
+			logger.Err(err).Str("game", game.ID.String()).Msg("error while recording game scores")
+		}
+
+		results := c.leaderboard(game, "# Final Leaderboard:\n\n")
+		if len(game.Guesses) == 0 {
+			results = "No one guessed any words."
+		}
+
+		// send message that the game has ended
+		err = c.postSystemMessage(game.ChatID, fmt.Sprintf("# Game Over! ⌛\n\nThe guessing game has ended. No one guessed all words in time.\n\n**The words were:** %v \n\n%v", strings.Join(game.Words, ", "), results), utils.SystemPayload{
+			Event:   utils.EVENT_COMMAND_REPLY,
+			Command: guessCommand{}.Name(),
+		})
+		if err != nil {
+			logger.Err(err).Str("game", game.ID.String()).Msg("error while announcing expired game")
+		}
+	}
+}
+
+// GetGameLeaderboard returns the all-time scores of the guessing games in the chat
+func (c *ChatService) GetGameLeaderboard(userId uuid.UUID, chatId uuid.UUID) ([]utils.GameScore, error) {
+	if !c.MemberOfChat(userId, chatId) {
+		return nil, utils.NewError("User is not a member of the chat", http.StatusUnauthorized)
+	}
+
+	scores, err := c.storage.GetGameLeaderboard(chatId, LEADERBOARD_SIZE)
+	if err != nil {
+		return nil, err
+	}
+
+	players := make([]uuid.UUID, 0, len(scores))
+	for _, score := range scores {
+		players = append(players, score.UserID)
+	}
+
+	names := c.displayNames(players)
+	for i := range scores {
+		scores[i].DisplayName = names[scores[i].UserID]
+	}
+
+	return scores, nil
+}
+
+// recordScores adds the results of a finished game to the all-time leaderboard
+func (c *ChatService) recordScores(game *utils.Game) error {
+	players, userGuesses := gameResults(game)
+	if len(players) == 0 {
+		return nil
+	}
+
+	best := userGuesses[players[0]]
+	scores := make([]utils.GameScore, 0, len(players))
+	for _, userId := range players {
+		score := utils.GameScore{
+			ChatID: game.ChatID,
+			UserID: userId,
+			Words:  userGuesses[userId],
+			Games:  1,
+		}
+		if userGuesses[userId] == best {
+			score.Wins = 1
+		}
+		scores = append(scores, score)
+	}
+
+	return c.storage.RecordGameScores(game.ChatID, scores)
+}
+
+// leaderboard lists how many words every player has guessed, the best player first
+func (c *ChatService) leaderboard(game *utils.Game, title string) string {
+	players, userGuesses := gameResults(game)
+	names := c.displayNames(players)
+
+	var text strings.Builder
+	text.WriteString(title)
+	for _, userId := range players {
+		text.WriteString(fmt.Sprintf("%s: %d words\n\n", names[userId], userGuesses[userId]))
+	}
+	return text.String()
+}
+
+// gameResults returns the players ordered by the number of guessed words
+func gameResults(game *utils.Game) ([]uuid.UUID, map[uuid.UUID]int) {
+	players := []uuid.UUID{}
+	userGuesses := make(map[uuid.UUID]int)
+	for _, guess := range game.Guesses {
+		if _, exists := userGuesses[guess.UserID]; !exists {
+			players = append(players, guess.UserID)
+		}
+		userGuesses[guess.UserID]++
+	}
+
+	slices.SortStableFunc(players, func(a, b uuid.UUID) int { return userGuesses[b] - userGuesses[a] })
+	return players, userGuesses
+}
+
+// displayNames returns the names of the users, users that can not be found are mentioned by id
+func (c *ChatService) displayNames(userIds []uuid.UUID) map[uuid.UUID]string {
+	names := make(map[uuid.UUID]string, len(userIds))
+	for _, userId := range userIds {
+		names[userId] = "@" + userId.String()
+	}
+
+	if len(userIds) == 0 || c.auth == nil {
+		return names
+	}
+
+	users, err := c.auth.GetUsers(userIds...)
+	if err != nil {
+		logger.Err(err).Msg("error while fetching display names")
+		return names
+	}
+
+	for id, user := range users {
+		name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+		if len(name) > 0 {
+			names[id] = name
+		}
+	}
+	return names
+}
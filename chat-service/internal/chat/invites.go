@@ -0,0 +1,195 @@
+package chat
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+const (
+	MAX_INVITE_USES = 1000
+)
+
+// CreateInvite creates an invite link for a group chat, every member can invite others
+func (c *ChatService) CreateInvite(userId uuid.UUID, chatId uuid.UUID, expiresAt *time.Time, maxUses int) (*utils.Invite, error) {
+	_, err := c.groupChat(userId, chatId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, utils.NewError("expires_at has to be in the future", http.StatusBadRequest)
+	}
+
+	if maxUses < 0 || maxUses > MAX_INVITE_USES {
+		return nil, utils.NewError(fmt.Sprintf("max_uses has to be between 0 and %d", MAX_INVITE_USES), http.StatusBadRequest)
+	}
+
+	invite := utils.Invite{
+		ID:        uuid.New(),
+		ChatID:    chatId,
+		CreatedBy: userId,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+		MaxUses:   maxUses,
+	}
+
+	err = c.storage.SaveInvite(invite)
+	if err != nil {
+		return nil, err
+	}
+
+	invite.Token = c.inviteToken(invite.ID)
+	return &invite, nil
+}
+
+// GetInvites returns the invites of the chat that can still be used
+func (c *ChatService) GetInvites(userId uuid.UUID, chatId uuid.UUID) ([]utils.Invite, error) {
+	_, err := c.groupChat(userId, chatId)
+	if err != nil {
+		return nil, err
+	}
+
+	invites, err := c.storage.GetInvites(chatId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := []utils.Invite{}
+	for _, invite := range invites {
+		if invite.Active(now) {
+			invite.Token = c.inviteToken(invite.ID)
+			active = append(active, invite)
+		}
+	}
+
+	return active, nil
+}
+
+// RevokeInvite invalidates an invite, only the member who created it and admins can revoke it
+func (c *ChatService) RevokeInvite(userId uuid.UUID, chatId uuid.UUID, inviteId uuid.UUID) error {
+	chat, err := c.groupChat(userId, chatId)
+	if err != nil {
+		return err
+	}
+
+	invite, err := c.storage.GetInvite(inviteId)
+	if err != nil || invite.ChatID != chatId {
+		return utils.NewError("invite not found", http.StatusNotFound)
+	}
+
+	if invite.CreatedBy != userId && !chat.HasRole(userId, utils.ROLE_ADMIN) {
+		return utils.NewError("only admins can revoke invites of other members", http.StatusForbidden)
+	}
+
+	revoked, err := c.storage.RevokeInvite(chatId, inviteId)
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return utils.NewError("invite is already revoked", http.StatusConflict)
+	}
+
+	return nil
+}
+
+// JoinChat adds the user to the chat of the invite. Members joining again do not use up the invite
+func (c *ChatService) JoinChat(userId uuid.UUID, token string) (*utils.Chat, error) {
+	inviteId, ok := c.parseInviteToken(token)
+	if !ok {
+		return nil, utils.NewError("invalid invite", http.StatusNotFound)
+	}
+
+	invite, err := c.storage.GetInvite(inviteId)
+	if err != nil {
+		return nil, utils.NewError("invalid invite", http.StatusNotFound)
+	}
+
+	chat, err := c.storage.GetChat(invite.ChatID)
+	if err != nil {
+		return nil, utils.NewError("chat not found", http.StatusNotFound)
+	}
+
+	if chat.RoleOf(userId) != "" {
+		return chat, nil
+	}
+
+	// the invite is counted atomically, so it can not be used more often than allowed
+	used, err := c.storage.UseInvite(inviteId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !used {
+		return nil, utils.NewError("invite is expired, revoked or used up", http.StatusGone)
+	}
+
+	err = c.storage.AddChatMembers(chat.ID, []uuid.UUID{userId})
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.postSystemMessage(chat.ID, fmt.Sprintf("@%s joined the chat with an invite link.", userId), utils.SystemPayload{
+		Event: utils.EVENT_MEMBER_JOINED,
+		Actor: &userId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chat.AddMembers(userId)
+	return chat, nil
+}
+
+// inviteToken signs the invite id, so tokens can not be guessed from other invites
+func (c *ChatService) inviteToken(inviteId uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(inviteId[:]) + "." + base64.RawURLEncoding.EncodeToString(c.inviteSignature(inviteId))
+}
+
+func (c *ChatService) parseInviteToken(token string) (uuid.UUID, bool) {
+	id, signature, found := strings.Cut(token, ".")
+	if !found {
+		return uuid.Nil, false
+	}
+
+	idBytes, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	inviteId, err := uuid.FromBytes(idBytes)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	signatureBytes, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(signatureBytes, c.inviteSignature(inviteId)) {
+		return uuid.Nil, false
+	}
+
+	return inviteId, true
+}
+
+func (c *ChatService) inviteSignature(inviteId uuid.UUID) []byte {
+	mac := hmac.New(sha256.New, c.inviteSecret)
+	mac.Write(inviteId[:])
+	return mac.Sum(nil)
+}
+
+// randomSecret is only used if no secret is configured, its tokens are only valid in this process
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+}
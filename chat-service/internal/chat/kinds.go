@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+// messageKind is the kind of a message a user sends, messages with media are media messages
+func messageKind(media []uuid.UUID) utils.MessageKind {
+	if len(media) > 0 {
+		return utils.KIND_MEDIA
+	}
+	return utils.KIND_TEXT
+}
+
+// validateMessage checks that the payload matches the kind of the message before it is stored
+func (c *ChatService) validateMessage(message utils.Message) error {
+	system := message.SenderID == uuid.MustParse(utils.AIChat)
+	payload := message.Payload
+	if payload == nil {
+		payload = &utils.MessagePayload{}
+	}
+
+	// only the payload of the kind may be set
+	set := map[utils.MessageKind]bool{
+		utils.KIND_SYSTEM:  payload.System != nil,
+		utils.KIND_COMMAND: payload.Command != nil,
+		utils.KIND_AI:      payload.AI != nil,
+	}
+	for kind, ok := range set {
+		if ok && kind != message.Kind {
+			return utils.NewError(fmt.Sprintf("a %s message can not have a %s payload", message.Kind, kind), http.StatusBadRequest)
+		}
+	}
+
+	switch message.Kind {
+	case utils.KIND_TEXT:
+		if len(strings.TrimSpace(message.Content)) == 0 {
+			return utils.NewError("a text message needs content", http.StatusBadRequest)
+		}
+		if len(message.Media) > 0 {
+			return utils.NewError("a text message can not have media", http.StatusBadRequest)
+		}
+	case utils.KIND_MEDIA:
+		if len(message.Media) == 0 {
+			return utils.NewError("a media message needs media", http.StatusBadRequest)
+		}
+	case utils.KIND_POLL:
+		if message.Poll == nil {
+			return utils.NewError("a poll message needs a poll", http.StatusBadRequest)
+		}
+	case utils.KIND_COMMAND:
+		if payload.Command == nil {
+			return utils.NewError("a command message needs a command payload", http.StatusBadRequest)
+		}
+		if _, exists := c.commands[payload.Command.Name]; !exists {
+			return utils.NewError("command not supported", http.StatusBadRequest)
+		}
+		if len(message.Media) > 0 {
+			return utils.NewError("a command can not have media", http.StatusBadRequest)
+		}
+	case utils.KIND_SYSTEM:
+		if payload.System == nil || payload.System.Event == "" {
+			return utils.NewError("a system message needs an event", http.StatusBadRequest)
+		}
+	case utils.KIND_AI:
+		if payload.AI == nil {
+			return utils.NewError("an AI message needs an AI payload", http.StatusBadRequest)
+		}
+	default:
+		return utils.NewError(fmt.Sprintf("unknown message kind %q", message.Kind), http.StatusBadRequest)
+	}
+
+	// users can not post messages in the name of the chat service and the other way round
+	if system != (message.Kind == utils.KIND_SYSTEM || message.Kind == utils.KIND_AI) {
+		return utils.NewError(fmt.Sprintf("%s messages can not be sent by this sender", message.Kind), http.StatusForbidden)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+const (
+	// previews are fetched one after another, so a message with many links does not start many requests
+	MAX_LINK_PREVIEWS = 3
+)
+
+var linkPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// WithUnfurler enables link previews, messages get no previews without an unfurler
+func WithUnfurler(unfurler utils.Unfurler) Option {
+	return func(c *ChatService) {
+		c.unfurler = unfurler
+	}
+}
+
+// findLinks returns the distinct links of the content in the order they appear
+func findLinks(content string) []string {
+	links := []string{}
+	for _, link := range linkPattern.FindAllString(content, -1) {
+		// punctuation at the end belongs to the sentence, not to the link
+		link = strings.TrimRight(link, ".,;:!?)]}")
+		if !slices.Contains(links, link) {
+			links = append(links, link)
+		}
+		if len(links) == MAX_LINK_PREVIEWS {
+			break
+		}
+	}
+	return links
+}
+
+// unfurlLinks stores the previews of the links of a message. The previews are not stored if the message
+// was edited in the meantime, the edit unfurls the new content. replace removes previews of an earlier version
+func (c *ChatService) unfurlLinks(messageId uuid.UUID, content string, replace bool) {
+	links := findLinks(content)
+	if len(links) == 0 && !replace {
+		return
+	}
+
+	previews := []utils.LinkPreview{}
+	for _, link := range links {
+		preview, err := c.unfurler.Unfurl(link)
+		if err != nil || preview == nil {
+			continue
+		}
+		previews = append(previews, *preview)
+	}
+
+	if len(previews) == 0 && !replace {
+		return
+	}
+
+	// updatedAt is set, so the previews reach the clients like every other change of the message
+	_, err := c.storage.SetMessagePreviews(messageId, content, previews)
+	if err != nil {
+		logger.Err(err).Str("message", messageId.String()).Msg("failed to store link previews")
+	}
+}
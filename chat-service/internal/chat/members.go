@@ -0,0 +1,186 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+// AddMembers adds users to a group chat, only admins can add members
+func (c *ChatService) AddMembers(userId uuid.UUID, chatId uuid.UUID, members []uuid.UUID) (*utils.Chat, error) {
+	chat, err := c.groupChat(userId, chatId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !chat.HasRole(userId, utils.ROLE_ADMIN) {
+		return nil, utils.NewError("Only admins can add members", http.StatusForbidden)
+	}
+
+	// filter out duplicates and users that are already members
+	newMembers := []uuid.UUID{}
+	for _, member := range members {
+		if !slices.Contains(chat.Members, member) && !slices.Contains(newMembers, member) {
+			newMembers = append(newMembers, member)
+		}
+	}
+
+	if len(newMembers) == 0 {
+		return nil, utils.NewError("No new members to add", http.StatusBadRequest)
+	}
+
+	exists, err := c.auth.Exists(newMembers...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, utils.NewError("One or more members do not exist", http.StatusBadRequest)
+	}
+
+	err = c.storage.AddChatMembers(chatId, newMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.postSystemMessage(chatId, fmt.Sprintf("@%s added %s to the chat.", userId, mentions(newMembers)), utils.SystemPayload{
+		Event: utils.EVENT_MEMBERS_ADDED,
+		Actor: &userId,
+		Users: newMembers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chat.AddMembers(newMembers...)
+	return chat, nil
+}
+
+// RemoveMember removes a member from a group chat. Admins can remove members,
+// only the owner can remove admins and the owner can not be removed at all
+func (c *ChatService) RemoveMember(userId uuid.UUID, chatId uuid.UUID, memberId uuid.UUID) (*utils.Chat, error) {
+	if userId == memberId {
+		return c.LeaveChat(userId, chatId)
+	}
+
+	chat, err := c.groupChat(userId, chatId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !chat.HasRole(userId, utils.ROLE_ADMIN) {
+		return nil, utils.NewError("Only admins can remove members", http.StatusForbidden)
+	}
+
+	switch chat.RoleOf(memberId) {
+	case "":
+		return nil, utils.NewError("User is not a member of the chat", http.StatusNotFound)
+	case utils.ROLE_OWNER:
+		return nil, utils.NewError("The owner can not be removed from the chat", http.StatusForbidden)
+	case utils.ROLE_ADMIN:
+		if !chat.HasRole(userId, utils.ROLE_OWNER) {
+			return nil, utils.NewError("Only the owner can remove admins", http.StatusForbidden)
+		}
+	}
+
+	err = c.storage.RemoveChatMember(chatId, memberId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.postSystemMessage(chatId, fmt.Sprintf("@%s removed @%s from the chat.", userId, memberId), utils.SystemPayload{
+		Event: utils.EVENT_MEMBER_REMOVED,
+		Actor: &userId,
+		Users: []uuid.UUID{memberId},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chat.Members = slices.DeleteFunc(chat.Members, func(id uuid.UUID) bool { return id == memberId })
+	delete(chat.Roles, memberId.String())
+	return chat, nil
+}
+
+// LeaveChat removes the user from a group chat. If the owner leaves, the ownership is passed
+// to the first admin or, if there is none, to the first member. The last member deletes the chat
+func (c *ChatService) LeaveChat(userId uuid.UUID, chatId uuid.UUID) (*utils.Chat, error) {
+	chat, err := c.groupChat(userId, chatId)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(chat.Members), func(id uuid.UUID) bool { return id == userId })
+	if len(remaining) == 0 {
+		return chat, c.storage.DeleteChat(chatId)
+	}
+
+	content := fmt.Sprintf("@%s left the chat.", userId)
+	event := utils.SystemPayload{Event: utils.EVENT_MEMBER_LEFT, Actor: &userId}
+
+	if chat.RoleOf(userId) == utils.ROLE_OWNER {
+		newOwner := remaining[0]
+		for _, member := range remaining {
+			if chat.RoleOf(member) == utils.ROLE_ADMIN {
+				newOwner = member
+				break
+			}
+		}
+
+		err = c.storage.SetChatRole(chatId, newOwner, utils.ROLE_OWNER)
+		if err != nil {
+			return nil, err
+		}
+
+		if chat.Roles == nil {
+			chat.Roles = map[string]utils.ChatRole{}
+		}
+		chat.Roles[newOwner.String()] = utils.ROLE_OWNER
+		content += fmt.Sprintf(" @%s is the new owner.", newOwner)
+		event.Users = []uuid.UUID{newOwner}
+	}
+
+	err = c.storage.RemoveChatMember(chatId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.postSystemMessage(chatId, content, event)
+	if err != nil {
+		return nil, err
+	}
+
+	chat.Members = remaining
+	delete(chat.Roles, userId.String())
+	return chat, nil
+}
+
+// groupChat loads a chat whose members can be changed, the user has to be a member of it
+func (c *ChatService) groupChat(userId uuid.UUID, chatId uuid.UUID) (*utils.Chat, error) {
+	chat, err := c.storage.GetChat(chatId)
+	if err != nil {
+		return nil, utils.NewError("Chat not found.", http.StatusNotFound)
+	}
+
+	if chat.RoleOf(userId) == "" {
+		return nil, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	if chat.Name == "Direct Chat" || chat.ID == userId {
+		return nil, utils.NewError("members of this chat can not be changed", http.StatusBadRequest)
+	}
+
+	return chat, nil
+}
+
+func mentions(users []uuid.UUID) string {
+	names := make([]string, 0, len(users))
+	for _, user := range users {
+		names = append(names, "@"+user.String())
+	}
+	return strings.Join(names, ", ")
+}
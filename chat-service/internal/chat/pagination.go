@@ -0,0 +1,165 @@
+package chat
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+// GetMessagesPage returns a page of messages before or after a cursor, or around a message.
+// Cursors are either returned by a previous page or the id of a message in the chat
+func (c *ChatService) GetMessagesPage(userId uuid.UUID, chatId uuid.UUID, query utils.MessagePageQuery) (*utils.MessagePage, error) {
+	if !c.MemberOfChat(userId, chatId) {
+		return nil, utils.NewError("User is not a member of the chat", http.StatusUnauthorized)
+	}
+
+	limit := max(query.Limit, 1)
+
+	var (
+		older, newer []utils.Message
+		hasOlder     bool
+		hasNewer     bool
+	)
+
+	switch {
+	case query.Around != nil:
+		cursor, err := c.messageCursor(chatId, *query.Around)
+		if err != nil {
+			return nil, err
+		}
+
+		// the message itself is part of the older half
+		half := limit / 2
+		older, hasOlder, err = c.loadMessages(chatId, cursor, true, true, limit-half)
+		if err != nil {
+			return nil, err
+		}
+		newer, hasNewer, err = c.loadMessages(chatId, cursor, false, false, half)
+		if err != nil {
+			return nil, err
+		}
+
+	case query.Before != "":
+		cursor, err := c.parseCursor(chatId, query.Before)
+		if err != nil {
+			return nil, err
+		}
+
+		older, hasOlder, err = c.loadMessages(chatId, cursor, true, false, limit)
+		if err != nil {
+			return nil, err
+		}
+		// at least the message the cursor points at is newer
+		hasNewer = true
+
+	case query.After != "":
+		cursor, err := c.parseCursor(chatId, query.After)
+		if err != nil {
+			return nil, err
+		}
+
+		newer, hasNewer, err = c.loadMessages(chatId, cursor, false, false, limit)
+		if err != nil {
+			return nil, err
+		}
+		hasOlder = true
+
+	default:
+		// start at the latest message
+		var err error
+		latest := utils.MessageCursor{Timestamp: time.Now(), ID: uuid.Max}
+		older, hasOlder, err = c.loadMessages(chatId, latest, true, true, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	page := &utils.MessagePage{
+		Messages: append(older, newer...),
+	}
+
+	if len(page.Messages) > 0 {
+		if hasOlder {
+			page.PrevCursor = encodeCursor(page.Messages[0])
+		}
+		if hasNewer {
+			page.NextCursor = encodeCursor(page.Messages[len(page.Messages)-1])
+		}
+	}
+
+	return page, nil
+}
+
+// loadMessages loads up to limit messages and reports if there are more messages in that direction
+func (c *ChatService) loadMessages(chatId uuid.UUID, cursor utils.MessageCursor, older bool, inclusive bool, limit int) ([]utils.Message, bool, error) {
+	if limit <= 0 {
+		return []utils.Message{}, true, nil
+	}
+
+	// fetch one more message than needed to know if there are more
+	messages, err := c.storage.GetChatMessagesFrom(chatId, cursor, older, inclusive, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(messages) <= limit {
+		return messages, false, nil
+	}
+
+	// messages are in chronological order, drop the one furthest away from the cursor
+	if older {
+		return messages[1:], true, nil
+	}
+	return messages[:limit], true, nil
+}
+
+// parseCursor decodes a cursor returned with a previous page, a plain message id is accepted as well
+func (c *ChatService) parseCursor(chatId uuid.UUID, cursor string) (utils.MessageCursor, error) {
+	if messageId, err := uuid.Parse(cursor); err == nil {
+		return c.messageCursor(chatId, messageId)
+	}
+
+	invalid := utils.NewError("invalid cursor", http.StatusBadRequest)
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return utils.MessageCursor{}, invalid
+	}
+
+	timestamp, id, found := strings.Cut(string(decoded), "_")
+	if !found {
+		return utils.MessageCursor{}, invalid
+	}
+
+	nanos, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return utils.MessageCursor{}, invalid
+	}
+
+	messageId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.MessageCursor{}, invalid
+	}
+
+	return utils.MessageCursor{Timestamp: time.Unix(0, nanos), ID: messageId}, nil
+}
+
+// messageCursor creates a cursor pointing at a message of the chat
+func (c *ChatService) messageCursor(chatId uuid.UUID, messageId uuid.UUID) (utils.MessageCursor, error) {
+	message, err := c.storage.GetMessage(messageId)
+	if err != nil || message.ChatID.String() != chatId.String() {
+		return utils.MessageCursor{}, utils.NewError("message not found", http.StatusNotFound)
+	}
+	return utils.MessageCursor{Timestamp: message.Timestamp, ID: message.ID}, nil
+}
+
+func encodeCursor(message utils.Message) string {
+	cursor := fmt.Sprintf("%d_%s", message.Timestamp.UnixNano(), message.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+}
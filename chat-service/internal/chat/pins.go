@@ -0,0 +1,125 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+// PinMessage pins a message of the chat, every member can pin messages
+func (c *ChatService) PinMessage(userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) (*utils.Pin, error) {
+	chat, message, err := c.pinTarget(userId, chatId, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Deleted {
+		return nil, utils.NewError("cannot pin a deleted message", http.StatusBadRequest)
+	}
+
+	if slices.ContainsFunc(chat.Pins, func(p utils.Pin) bool { return p.MessageID == messageId }) {
+		return nil, utils.NewError("message is already pinned", http.StatusConflict)
+	}
+
+	if len(chat.Pins) >= c.maxPins {
+		return nil, utils.NewError(fmt.Sprintf("a chat can not have more than %d pinned messages", c.maxPins), http.StatusConflict)
+	}
+
+	pin := utils.Pin{
+		MessageID: messageId,
+		PinnedBy:  userId,
+		PinnedAt:  time.Now(),
+	}
+
+	// the check above can race with other pins, the storage only pins if there is still room
+	pinned, err := c.storage.AddPin(chatId, pin, c.maxPins)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pinned {
+		return nil, utils.NewError("message could not be pinned", http.StatusConflict)
+	}
+
+	err = c.postSystemMessage(chatId, fmt.Sprintf("@%s pinned a message.", userId), utils.SystemPayload{
+		Event:     utils.EVENT_MESSAGE_PINNED,
+		Actor:     &userId,
+		MessageID: &messageId,
+	})
+	return &pin, err
+}
+
+// UnpinMessage removes a pin, only the member who pinned the message and admins can remove it
+func (c *ChatService) UnpinMessage(userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) error {
+	chat, err := c.storage.GetChat(chatId)
+	if err != nil {
+		return utils.NewError("chat not found", http.StatusNotFound)
+	}
+
+	if chat.RoleOf(userId) == "" {
+		return utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	index := slices.IndexFunc(chat.Pins, func(p utils.Pin) bool { return p.MessageID == messageId })
+	if index < 0 {
+		return utils.NewError("message is not pinned", http.StatusNotFound)
+	}
+
+	if chat.Pins[index].PinnedBy != userId && !chat.HasRole(userId, utils.ROLE_ADMIN) {
+		return utils.NewError("only admins can remove pins of other members", http.StatusForbidden)
+	}
+
+	_, err = c.storage.RemovePin(chatId, messageId)
+	return err
+}
+
+// GetPins returns the pinned messages of the chat, the latest pin first
+func (c *ChatService) GetPins(userId uuid.UUID, chatId uuid.UUID) ([]utils.PinnedMessage, error) {
+	chat, err := c.storage.GetChat(chatId)
+	if err != nil {
+		return nil, utils.NewError("chat not found", http.StatusNotFound)
+	}
+
+	if chat.RoleOf(userId) == "" {
+		return nil, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	pins := []utils.PinnedMessage{}
+	for i := len(chat.Pins) - 1; i >= 0; i-- {
+		message, err := c.storage.GetMessage(chat.Pins[i].MessageID)
+		if err != nil {
+			// the message does not exist anymore
+			continue
+		}
+
+		pins = append(pins, utils.PinnedMessage{
+			Pin:     chat.Pins[i],
+			Message: message,
+		})
+	}
+
+	return pins, nil
+}
+
+// pinTarget loads the chat and the message, ensuring the user is a member and the message belongs to the chat
+func (c *ChatService) pinTarget(userId uuid.UUID, chatId uuid.UUID, messageId uuid.UUID) (*utils.Chat, utils.Message, error) {
+	chat, err := c.storage.GetChat(chatId)
+	if err != nil {
+		return nil, utils.Message{}, utils.NewError("chat not found", http.StatusNotFound)
+	}
+
+	if chat.RoleOf(userId) == "" {
+		return nil, utils.Message{}, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	message, err := c.storage.GetMessage(messageId)
+	if err != nil || message.ChatID != chatId {
+		return nil, utils.Message{}, utils.NewError("message not found", http.StatusNotFound)
+	}
+
+	return chat, message, nil
+}
@@ -0,0 +1,202 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+const (
+	MIN_POLL_OPTIONS = 2
+	MAX_POLL_OPTIONS = 10
+)
+
+// CreatePoll sends a poll to the chat, the question is used as the content of the message
+func (c *ChatService) CreatePoll(userId uuid.UUID, chatId uuid.UUID, poll utils.Poll) (*utils.Message, error) {
+	// handle ai chat
+	if chatId == userId {
+		return nil, utils.NewError("polls are not supported for AI chat", http.StatusBadRequest)
+	}
+
+	now := time.Now()
+	poll, err := validatePoll(poll, now)
+	if err != nil {
+		return nil, err
+	}
+
+	member := c.MemberOfChat(userId, chatId)
+	if !member {
+		return nil, utils.NewError("User is not a member of the chat", http.StatusUnauthorized)
+	}
+
+	message := utils.Message{
+		ID:        uuid.New(),
+		ChatID:    chatId,
+		SenderID:  userId,
+		Timestamp: now,
+		UpdatedAt: now,
+		Content:   poll.Question,
+		Poll:      &poll,
+		Kind:      utils.KIND_POLL,
+	}
+
+	err = c.validateMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.storage.SaveMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.storage.UpdateChatActivity(chatId)
+	return &message, err
+}
+
+// validatePoll checks the question and options and resets the tallies of a new poll
+func validatePoll(poll utils.Poll, now time.Time) (utils.Poll, error) {
+	poll.Question = strings.TrimSpace(poll.Question)
+	if len(poll.Question) == 0 {
+		return poll, utils.NewError("the question of the poll is empty", http.StatusBadRequest)
+	}
+
+	if len(poll.Options) < MIN_POLL_OPTIONS || len(poll.Options) > MAX_POLL_OPTIONS {
+		return poll, utils.NewError(fmt.Sprintf("a poll needs between %d and %d options", MIN_POLL_OPTIONS, MAX_POLL_OPTIONS), http.StatusBadRequest)
+	}
+
+	options := make([]utils.PollOption, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		text := strings.TrimSpace(option.Text)
+		if len(text) == 0 {
+			return poll, utils.NewError("poll options can not be empty", http.StatusBadRequest)
+		}
+
+		if slices.ContainsFunc(options, func(o utils.PollOption) bool { return strings.EqualFold(o.Text, text) }) {
+			return poll, utils.NewError("poll options have to be unique", http.StatusBadRequest)
+		}
+
+		options = append(options, utils.PollOption{Text: text, Voters: []uuid.UUID{}})
+	}
+
+	if poll.ClosesAt != nil && !poll.ClosesAt.After(now) {
+		return poll, utils.NewError("closes_at has to be in the future", http.StatusBadRequest)
+	}
+
+	poll.Options = options
+	poll.Closed = false
+	poll.ClosedAt = nil
+	poll.Voters = 0
+	return poll, nil
+}
+
+// Vote counts the vote of the user, every member can vote once and votes can not be changed
+func (c *ChatService) Vote(userId uuid.UUID, messageId uuid.UUID, options []int) (utils.Message, error) {
+	message, err := c.pollMessage(userId, messageId)
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	now := time.Now()
+	if !message.Poll.Open(now) {
+		return utils.Message{}, utils.NewError("the poll is closed", http.StatusConflict)
+	}
+
+	if len(options) == 0 || (!message.Poll.MultipleChoice && len(options) > 1) {
+		return utils.Message{}, utils.NewError("choose one option, or several if the poll allows multiple choices", http.StatusBadRequest)
+	}
+
+	options = slices.Clone(options)
+	slices.Sort(options)
+	if len(slices.Compact(options)) != len(options) {
+		return utils.Message{}, utils.NewError("options can only be chosen once", http.StatusBadRequest)
+	}
+
+	if options[0] < 0 || options[len(options)-1] >= len(message.Poll.Options) {
+		return utils.Message{}, utils.NewError("invalid option", http.StatusBadRequest)
+	}
+
+	vote := utils.PollVote{
+		ID:        uuid.New(),
+		MessageID: messageId,
+		UserID:    userId,
+		Options:   options,
+		Timestamp: now,
+	}
+
+	// the stored vote guarantees that every user is only counted once
+	saved, err := c.storage.SavePollVote(vote)
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	if !saved {
+		return utils.Message{}, utils.NewError("you have already voted", http.StatusConflict)
+	}
+
+	counted, err := c.storage.CountPollVote(vote, message.Poll.Anonymous)
+	if err != nil || !counted {
+		// the poll was closed in the meantime, the vote does not count
+		c.storage.DeletePollVote(messageId, userId)
+		if err != nil {
+			return utils.Message{}, err
+		}
+		return utils.Message{}, utils.NewError("the poll is closed", http.StatusConflict)
+	}
+
+	return c.storage.GetMessage(messageId)
+}
+
+// ClosePoll stops the voting and freezes the results, only the creator of the poll and admins can close it
+func (c *ChatService) ClosePoll(userId uuid.UUID, messageId uuid.UUID) (utils.Message, error) {
+	message, err := c.pollMessage(userId, messageId)
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	if message.SenderID != userId {
+		chat, err := c.storage.GetChat(message.ChatID)
+		if err != nil {
+			return utils.Message{}, utils.NewError("chat not found", http.StatusNotFound)
+		}
+
+		if !chat.HasRole(userId, utils.ROLE_ADMIN) {
+			return utils.Message{}, utils.NewError("only the creator of the poll and admins can close it", http.StatusForbidden)
+		}
+	}
+
+	closed, err := c.storage.ClosePoll(messageId, time.Now())
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	if !closed {
+		return utils.Message{}, utils.NewError("the poll is already closed", http.StatusConflict)
+	}
+
+	return c.storage.GetMessage(messageId)
+}
+
+// pollMessage returns the poll message if the user is allowed to see it
+func (c *ChatService) pollMessage(userId uuid.UUID, messageId uuid.UUID) (utils.Message, error) {
+	message, err := c.storage.GetMessage(messageId)
+	if err != nil {
+		return utils.Message{}, utils.NewError("message not found", http.StatusNotFound)
+	}
+
+	err = c.storage.MemberOfChat(userId, message.ChatID)
+	if err != nil {
+		return utils.Message{}, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	if message.Deleted || message.Poll == nil {
+		return utils.Message{}, utils.NewError("message is not a poll", http.StatusBadRequest)
+	}
+
+	return message, nil
+}
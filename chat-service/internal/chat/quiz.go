@@ -0,0 +1,329 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+const (
+	QUIZ_QUESTIONS         = 5
+	QUIZ_QUESTION_DURATION = 20 * time.Second
+	// a quiz that is still waiting for its questions after this time is given up
+	QUIZ_START_TIMEOUT = 1 * time.Minute
+	QUIZ_INTERVAL      = 1 * time.Second
+	// correct answers get half of the points, the other half depends on how fast they were
+	QUIZ_POINTS = 1000
+)
+
+// quizCommand starts a trivia quiz about a topic
+type quizCommand struct{}
+
+func (quizCommand) Name() string { return "quiz" }
+
+func (quizCommand) Usage() string { return "/quiz [topic]" }
+
+func (quizCommand) Description() string {
+	return "Starts a multiple-choice quiz about a topic, the fastest correct answers get the most points"
+}
+
+func (quizCommand) ParseArgs(args string) ([]string, error) {
+	args = strings.TrimSpace(args)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("a topic is required")
+	}
+	return []string{args}, nil
+}
+
+func (quizCommand) Run(ctx CommandContext) error {
+	quiz, err := ctx.chat.storage.GetActiveQuiz(ctx.ChatID)
+	if err != nil {
+		return err
+	}
+
+	if quiz != nil {
+		return ctx.Reply("A quiz is already running. Use `/answer [letter]` to answer the current question.")
+	}
+
+	return ctx.chat.startQuiz(ctx, ctx.Args[0])
+}
+
+// answerCommand answers the current question of the running quiz
+type answerCommand struct{}
+
+func (answerCommand) Name() string { return "answer" }
+
+func (answerCommand) Usage() string { return "/answer [A|B|C|D]" }
+
+func (answerCommand) Description() string { return "Answers the current question of the quiz" }
+
+func (answerCommand) ParseArgs(args string) ([]string, error) {
+	args = strings.ToUpper(strings.TrimSpace(args))
+	if len(args) != 1 || args[0] < 'A' || args[0] > 'D' {
+		return nil, fmt.Errorf("the answer has to be one of A, B, C or D")
+	}
+	return []string{args}, nil
+}
+
+func (answerCommand) Run(ctx CommandContext) error {
+	quiz, err := ctx.chat.storage.GetActiveQuiz(ctx.ChatID)
+	if err != nil {
+		return err
+	}
+
+	if quiz == nil {
+		return ctx.Reply("There is no quiz running. Use `/quiz [topic]` to start one.")
+	}
+
+	return ctx.chat.answerQuestion(ctx, quiz, int(ctx.Args[0][0]-'A'))
+}
+
+func (c *ChatService) startQuiz(ctx CommandContext, topic string) error {
+	now := time.Now()
+	quiz := utils.Quiz{
+		ID:        uuid.New(),
+		ChatID:    ctx.ChatID,
+		Topic:     topic,
+		Questions: []utils.QuizQuestion{},
+		Answers:   []utils.QuizAnswer{},
+		Status:    utils.QUIZ_STARTING,
+		StartedBy: ctx.UserID,
+		StartedAt: now,
+		Deadline:  now.Add(QUIZ_START_TIMEOUT),
+		Active:    true,
+	}
+
+	// another replica may have started a quiz at the same time
+	started, err := c.storage.StartQuiz(quiz)
+	if err != nil {
+		return err
+	}
+
+	if !started {
+		return ctx.Reply("A quiz is already starting. Please wait a moment...")
+	}
+
+	err = ctx.Reply("Starting a new quiz. Please wait a moment...")
+	if err != nil {
+		return err
+	}
+
+	questions, err := c.ai.GenerateQuiz(topic, QUIZ_QUESTIONS)
+	if err == nil {
+		questions = validQuestions(questions)
+	}
+
+	if err != nil || len(questions) == 0 {
+		_, err = c.storage.FinishQuiz(quiz.ID, utils.QUIZ_FAILED, time.Now())
+		if err != nil {
+			return err
+		}
+		return ctx.Reply("Failed to generate questions for that topic. Please try again.")
+	}
+
+	now = time.Now()
+	running, err := c.storage.SetQuizQuestions(quiz.ID, questions, now, now.Add(QUIZ_QUESTION_DURATION))
+	if err != nil {
+		return err
+	}
+
+	// the quiz was given up while the questions were generated
+	if !running {
+		return nil
+	}
+
+	quiz.Questions = questions
+	return ctx.Reply(fmt.Sprintf("# A new quiz has started about **%s**. \nThere are **%v** questions.\n\n%s", topic, len(questions), formatQuestion(&quiz, 0)))
+}
+
+// validQuestions leaves out questions the players could not answer
+func validQuestions(questions []utils.QuizQuestion) []utils.QuizQuestion {
+	valid := []utils.QuizQuestion{}
+	for _, question := range questions {
+		if len(question.Question) == 0 || len(question.Options) < 2 || len(question.Options) > 4 {
+			continue
+		}
+		if question.Answer < 0 || question.Answer >= len(question.Options) {
+			continue
+		}
+		valid = append(valid, question)
+	}
+	return valid
+}
+
+// answerQuestion records the answer of the player, the result is only revealed when the question is over
+func (c *ChatService) answerQuestion(ctx CommandContext, quiz *utils.Quiz, option int) error {
+	if quiz.Status == utils.QUIZ_STARTING {
+		return ctx.Reply("The quiz is still starting. Please wait a moment...")
+	}
+
+	if quiz.Current >= len(quiz.Questions) {
+		return ctx.Reply("The quiz is already over.")
+	}
+
+	question := quiz.Questions[quiz.Current]
+	if option >= len(question.Options) {
+		return ctx.Reply(fmt.Sprintf("This question only has the options A to %c.", 'A'+len(question.Options)-1))
+	}
+
+	now := time.Now()
+	answer := utils.QuizAnswer{
+		Question:  quiz.Current,
+		UserID:    ctx.UserID,
+		Option:    option,
+		Correct:   option == question.Answer,
+		Timestamp: now,
+	}
+	if answer.Correct {
+		answer.Points = quizPoints(now.Sub(quiz.QuestionStartedAt))
+	}
+
+	// the answer only counts if the question is still open and the player did not answer it yet
+	added, err := c.storage.AddQuizAnswer(quiz.ID, answer)
+	if err != nil {
+		return err
+	}
+
+	if !added {
+		return ctx.Reply(fmt.Sprintf("@%s you already answered this question or the time is up.", ctx.UserID))
+	}
+	return nil
+}
+
+// quizPoints rewards faster correct answers with more points
+func quizPoints(elapsed time.Duration) int {
+	remaining := 1 - float64(elapsed)/float64(QUIZ_QUESTION_DURATION)
+	remaining = max(0, min(1, remaining))
+	return QUIZ_POINTS/2 + int(float64(QUIZ_POINTS/2)*remaining)
+}
+
+// AdvanceQuizzes reveals the answers of questions whose time is up and asks the next question
+// until the context is cancelled. Every quiz is claimed first, so several replicas can run at the same time
+func (c *ChatService) AdvanceQuizzes(ctx context.Context) {
+	ticker := time.NewTicker(QUIZ_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.advanceQuizzes(time.Now())
+		}
+	}
+}
+
+func (c *ChatService) advanceQuizzes(now time.Time) {
+	for {
+		quiz, err := c.storage.ClaimQuizQuestion(now, now.Add(QUIZ_QUESTION_DURATION))
+		if err != nil {
+			logger.Err(err).Msg("error while claiming quiz questions")
+			return
+		}
+
+		if quiz == nil {
+			return
+		}
+
+		err = c.advanceQuiz(quiz, now)
+		if err != nil {
+			logger.Err(err).Str("quiz", quiz.ID.String()).Msg("error while advancing quiz")
+		}
+	}
+}
+
+// advanceQuiz announces the end of the current question of the claimed quiz
+func (c *ChatService) advanceQuiz(quiz *utils.Quiz, now time.Time) error {
+	// the questions and results are replies of the quiz command, even though no user sent it
+	quizReply := utils.SystemPayload{Event: utils.EVENT_COMMAND_REPLY, Command: quizCommand{}.Name()}
+
+	// quizzes that never got their questions were not announced
+	if quiz.Status == utils.QUIZ_STARTING {
+		failed, err := c.storage.FinishQuiz(quiz.ID, utils.QUIZ_FAILED, now)
+		if err != nil || !failed {
+			return err
+		}
+		return c.postSystemMessage(quiz.ChatID, "The quiz could not be started in time. Please try again.", quizReply)
+	}
+
+	result := ""
+	if quiz.Current < len(quiz.Questions) {
+		result = formatResult(quiz, quiz.Current) + "\n\n"
+	}
+
+	next := quiz.Current + 1
+	if next < len(quiz.Questions) {
+		return c.postSystemMessage(quiz.ChatID, result+formatQuestion(quiz, next), quizReply)
+	}
+
+	finished, err := c.storage.FinishQuiz(quiz.ID, utils.QUIZ_FINISHED, now)
+	if err != nil || !finished {
+		return err
+	}
+
+	return c.postSystemMessage(quiz.ChatID, result+c.quizRanking(quiz), quizReply)
+}
+
+func formatQuestion(quiz *utils.Quiz, index int) string {
+	question := quiz.Questions[index]
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("## Question %d/%d\n\n%s\n\n", index+1, len(quiz.Questions), question.Question))
+	for i, option := range question.Options {
+		text.WriteString(fmt.Sprintf("**%c)** %s\n\n", 'A'+i, option))
+	}
+	text.WriteString(fmt.Sprintf("Use: `/answer [letter]` - you have %s to answer.", QUIZ_QUESTION_DURATION))
+	return text.String()
+}
+
+func formatResult(quiz *utils.Quiz, index int) string {
+	question := quiz.Questions[index]
+
+	answers, correct := 0, 0
+	for _, answer := range quiz.Answers {
+		if answer.Question != index {
+			continue
+		}
+		answers++
+		if answer.Correct {
+			correct++
+		}
+	}
+
+	return fmt.Sprintf("⌛ Time is up! The correct answer was **%c) %s**. %d of %d answers were correct.", 'A'+question.Answer, question.Options[question.Answer], correct, answers)
+}
+
+// quizRanking lists the points of every player, the best player first
+func (c *ChatService) quizRanking(quiz *utils.Quiz) string {
+	if len(quiz.Answers) == 0 {
+		return "# Quiz Over!\n\nNo one answered any questions."
+	}
+
+	players := []uuid.UUID{}
+	points := make(map[uuid.UUID]int)
+	correct := make(map[uuid.UUID]int)
+	for _, answer := range quiz.Answers {
+		if _, exists := points[answer.UserID]; !exists {
+			players = append(players, answer.UserID)
+		}
+		points[answer.UserID] += answer.Points
+		if answer.Correct {
+			correct[answer.UserID]++
+		}
+	}
+
+	slices.SortStableFunc(players, func(a, b uuid.UUID) int { return points[b] - points[a] })
+	names := c.displayNames(players)
+
+	var text strings.Builder
+	text.WriteString("# 🏆 Quiz Over! Final Ranking:\n\n")
+	for i, userId := range players {
+		text.WriteString(fmt.Sprintf("%d. %s: %d points (%d/%d correct)\n\n", i+1, names[userId], points[userId], correct[userId], len(quiz.Questions)))
+	}
+	return text.String()
+}
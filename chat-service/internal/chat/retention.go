@@ -0,0 +1,118 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+const (
+	RETENTION_SWEEP_INTERVAL = 1 * time.Minute
+	// disappearing messages can not be shorter than the sweep interval
+	MIN_MESSAGE_TTL    = 60
+	MAX_RETENTION_DAYS = 3650
+)
+
+// SetRetention changes how long the messages of a chat are kept, an empty retention keeps them forever.
+// Only admins can change the retention of group chats, in direct chats both members can
+func (c *ChatService) SetRetention(userId uuid.UUID, chatId uuid.UUID, retention utils.Retention) (*utils.Chat, error) {
+	chat, err := c.storage.GetChat(chatId)
+	if err != nil {
+		return nil, utils.NewError("chat not found", http.StatusNotFound)
+	}
+
+	if chat.RoleOf(userId) == "" {
+		return nil, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	if chat.Name != "Direct Chat" && !chat.HasRole(userId, utils.ROLE_ADMIN) {
+		return nil, utils.NewError("Only admins can change the retention", http.StatusForbidden)
+	}
+
+	if retention.Days < 0 || retention.Days > MAX_RETENTION_DAYS {
+		return nil, utils.NewError(fmt.Sprintf("days has to be between 0 and %d", MAX_RETENTION_DAYS), http.StatusBadRequest)
+	}
+
+	if retention.MessageTTL < 0 || retention.MessageTTL > 0 && retention.MessageTTL < MIN_MESSAGE_TTL {
+		return nil, utils.NewError(fmt.Sprintf("message_ttl has to be at least %d seconds", MIN_MESSAGE_TTL), http.StatusBadRequest)
+	}
+
+	content := fmt.Sprintf("@%s turned off message retention.", userId)
+	chat.Retention = nil
+	if retention.MaxAge() > 0 {
+		content = fmt.Sprintf("@%s changed the retention, messages are deleted after %s.", userId, describeRetention(retention))
+		chat.Retention = &retention
+	}
+
+	err = c.storage.SetChatRetention(chatId, chat.Retention)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.postSystemMessage(chatId, content, utils.SystemPayload{
+		Event: utils.EVENT_RETENTION_CHANGED,
+		Actor: &userId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+// SweepExpiredMessages deletes messages that are older than the retention of their chat until the context is cancelled.
+// Deleting a message twice has no effect, so several replicas can sweep at the same time
+func (c *ChatService) SweepExpiredMessages(ctx context.Context) {
+	ticker := time.NewTicker(RETENTION_SWEEP_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.expireMessages(time.Now())
+		}
+	}
+}
+
+func (c *ChatService) expireMessages(now time.Time) {
+	chats, err := c.storage.GetChatsWithRetention()
+	if err != nil {
+		logger.Err(err).Msg("error while fetching chats with retention")
+		return
+	}
+
+	for _, chat := range chats {
+		maxAge := chat.Retention.MaxAge()
+		if maxAge <= 0 {
+			continue
+		}
+
+		deleted, err := c.storage.ExpireMessages(chat.ID, now.Add(-maxAge))
+		if err != nil {
+			logger.Err(err).Str("chat", chat.ID.String()).Msg("error while deleting expired messages")
+			continue
+		}
+
+		if deleted > 0 {
+			logger.Debug().Str("chat", chat.ID.String()).Int64("count", deleted).Msg("deleted expired messages")
+		}
+	}
+}
+
+func describeRetention(retention utils.Retention) string {
+	maxAge := retention.MaxAge()
+	if maxAge%(24*time.Hour) == 0 {
+		days := int(maxAge / (24 * time.Hour))
+		if days == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", days)
+	}
+	return maxAge.String()
+}
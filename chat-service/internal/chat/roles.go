@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+// PromoteMember makes a member of the chat an admin, only the owner can promote members
+func (c *ChatService) PromoteMember(userId uuid.UUID, chatId uuid.UUID, memberId uuid.UUID) (*utils.Chat, error) {
+	return c.setRole(userId, chatId, memberId, utils.ROLE_ADMIN)
+}
+
+// DemoteMember makes an admin of the chat a plain member again, only the owner can demote admins
+func (c *ChatService) DemoteMember(userId uuid.UUID, chatId uuid.UUID, memberId uuid.UUID) (*utils.Chat, error) {
+	return c.setRole(userId, chatId, memberId, utils.ROLE_MEMBER)
+}
+
+func (c *ChatService) setRole(userId uuid.UUID, chatId uuid.UUID, memberId uuid.UUID, role utils.ChatRole) (*utils.Chat, error) {
+	chat, err := c.storage.GetChat(chatId)
+	if err != nil {
+		return nil, utils.NewError("chat not found", http.StatusNotFound)
+	}
+
+	if chat.RoleOf(userId) == "" {
+		return nil, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	if chat.Name == "Direct Chat" {
+		return nil, utils.NewError("direct chats have no roles", http.StatusBadRequest)
+	}
+
+	if !chat.HasRole(userId, utils.ROLE_OWNER) {
+		return nil, utils.NewError("Only the owner can change roles", http.StatusForbidden)
+	}
+
+	switch chat.RoleOf(memberId) {
+	case "":
+		return nil, utils.NewError("user is not a member of the chat", http.StatusNotFound)
+	case utils.ROLE_OWNER:
+		return nil, utils.NewError("the role of the owner can not be changed", http.StatusBadRequest)
+	}
+
+	err = c.storage.SetChatRole(chatId, memberId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat.Roles == nil {
+		chat.Roles = map[string]utils.ChatRole{}
+	}
+	chat.Roles[memberId.String()] = role
+	return chat, nil
+}
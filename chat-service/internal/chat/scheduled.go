@@ -0,0 +1,189 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+const (
+	DISPATCH_INTERVAL = 1 * time.Second
+	// time a dispatcher has to deliver a claimed message before another replica retries it
+	SCHEDULE_LEASE = 1 * time.Minute
+)
+
+// ScheduleMessage stores a message that is sent to the chat at sendAt
+func (c *ChatService) ScheduleMessage(userId uuid.UUID, chatId uuid.UUID, content string, media []uuid.UUID, replyTo *uuid.UUID, sendAt time.Time) (*utils.ScheduledMessage, error) {
+	if len(content) == 0 && len(media) == 0 {
+		return nil, utils.NewError("message content is empty", http.StatusBadRequest)
+	}
+
+	if !sendAt.After(time.Now()) {
+		return nil, utils.NewError("send_at has to be in the future", http.StatusBadRequest)
+	}
+
+	if chatId == userId {
+		return nil, utils.NewError("messages to the ai chat can not be scheduled", http.StatusBadRequest)
+	}
+
+	if !c.MemberOfChat(userId, chatId) {
+		return nil, utils.NewError("User is not a member of the chat", http.StatusUnauthorized)
+	}
+
+	if _, err := c.replyThread(chatId, replyTo); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	message := utils.ScheduledMessage{
+		ID:        uuid.New(),
+		ChatID:    chatId,
+		SenderID:  userId,
+		Content:   content,
+		Media:     media,
+		ReplyTo:   replyTo,
+		SendAt:    sendAt,
+		Status:    utils.SCHEDULED_PENDING,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err := c.storage.SaveScheduledMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// GetScheduledMessages returns the scheduled messages of the user that have not been sent yet
+func (c *ChatService) GetScheduledMessages(userId uuid.UUID) ([]utils.ScheduledMessage, error) {
+	return c.storage.GetScheduledMessages(userId)
+}
+
+// UpdateScheduledMessage changes the content or the time of a scheduled message.
+// Messages that failed to send are scheduled again
+func (c *ChatService) UpdateScheduledMessage(userId uuid.UUID, id uuid.UUID, content string, media []uuid.UUID, sendAt time.Time) (*utils.ScheduledMessage, error) {
+	message, err := c.ownScheduledMessage(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) == 0 && len(media) == 0 {
+		return nil, utils.NewError("message content is empty", http.StatusBadRequest)
+	}
+
+	if !sendAt.After(time.Now()) {
+		return nil, utils.NewError("send_at has to be in the future", http.StatusBadRequest)
+	}
+
+	message.Content = content
+	message.Media = media
+	message.SendAt = sendAt
+	message.Status = utils.SCHEDULED_PENDING
+	message.Error = ""
+	message.UpdatedAt = time.Now()
+
+	updated, err := c.storage.UpdateScheduledMessage(*message)
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return nil, utils.NewError("message is already being sent", http.StatusConflict)
+	}
+
+	return message, nil
+}
+
+// CancelScheduledMessage deletes a scheduled message before it is sent
+func (c *ChatService) CancelScheduledMessage(userId uuid.UUID, id uuid.UUID) (*utils.ScheduledMessage, error) {
+	message, err := c.ownScheduledMessage(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := c.storage.DeleteScheduledMessage(id, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !deleted {
+		return nil, utils.NewError("message is already being sent", http.StatusConflict)
+	}
+
+	return message, nil
+}
+
+// DispatchScheduledMessages sends due scheduled messages until the context is cancelled.
+// Every message is claimed before it is sent, so several replicas can dispatch at the same time
+func (c *ChatService) DispatchScheduledMessages(ctx context.Context) {
+	ticker := time.NewTicker(DISPATCH_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.dispatchDueMessages()
+		}
+	}
+}
+
+func (c *ChatService) dispatchDueMessages() {
+	for {
+		message, err := c.storage.ClaimScheduledMessage(time.Now(), SCHEDULE_LEASE)
+		if err != nil {
+			logger.Err(err).Msg("error while claiming scheduled messages")
+			return
+		}
+
+		if message == nil {
+			return
+		}
+
+		err = c.storage.FinishScheduledMessage(message.ID, c.deliverScheduledMessage(message))
+		if err != nil {
+			logger.Err(err).Str("id", message.ID.String()).Msg("error while finishing scheduled message")
+		}
+	}
+}
+
+// deliverScheduledMessage sends the message and returns the reason if it could not be sent
+func (c *ChatService) deliverScheduledMessage(message *utils.ScheduledMessage) string {
+	// a previous dispatcher may have sent the message without finishing it
+	if _, err := c.storage.GetMessage(message.ID); err == nil {
+		return ""
+	}
+
+	_, err := c.sendMessage(message.ID, message.SenderID, message.ChatID, message.Content, message.Media, message.ReplyTo, nil)
+	if err == nil {
+		return ""
+	}
+
+	var serviceError *utils.ServiceError
+	if errors.As(err, &serviceError) {
+		return serviceError.Err
+	}
+
+	logger.Err(err).Str("id", message.ID.String()).Msg("error while sending scheduled message")
+	return "message could not be sent"
+}
+
+func (c *ChatService) ownScheduledMessage(userId uuid.UUID, id uuid.UUID) (*utils.ScheduledMessage, error) {
+	message, err := c.storage.GetScheduledMessage(id)
+	if err != nil || message.SenderID != userId {
+		return nil, utils.NewError("scheduled message not found", http.StatusNotFound)
+	}
+
+	if message.Status == utils.SCHEDULED_SENDING {
+		return nil, utils.NewError("message is already being sent", http.StatusConflict)
+	}
+
+	return message, nil
+}
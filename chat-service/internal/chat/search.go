@@ -0,0 +1,143 @@
+package chat
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+const (
+	DEFAULT_SEARCH_LIMIT = 20
+	MAX_SEARCH_LIMIT     = 100
+	// number of characters shown around the first match of a search result
+	SNIPPET_RADIUS = 60
+)
+
+// SearchMessages runs a full text search over all chats the user is a member of.
+// If the query is limited to a chat, the user has to be a member of that chat
+func (c *ChatService) SearchMessages(userId uuid.UUID, query utils.SearchQuery) (*utils.SearchResults, error) {
+	query.Query = strings.TrimSpace(query.Query)
+	if len(query.Query) == 0 {
+		return nil, utils.NewError("search query is empty", http.StatusBadRequest)
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = DEFAULT_SEARCH_LIMIT
+	}
+	query.Limit = min(query.Limit, MAX_SEARCH_LIMIT)
+	query.Offset = max(query.Offset, 0)
+
+	if len(query.ChatIDs) > 0 {
+		for _, chatId := range query.ChatIDs {
+			if !c.MemberOfChat(userId, chatId) {
+				return nil, utils.NewError("not a member of that chat", http.StatusForbidden)
+			}
+		}
+	} else {
+		chatIds, err := c.storage.GetChatIDs(userId)
+		if err != nil {
+			return nil, err
+		}
+		query.ChatIDs = chatIds
+	}
+
+	results := &utils.SearchResults{
+		Results: []utils.SearchResult{},
+		Limit:   query.Limit,
+		Offset:  query.Offset,
+	}
+
+	if len(query.ChatIDs) == 0 {
+		return results, nil
+	}
+
+	found, total, err := c.storage.SearchMessages(query)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := searchTerms(query.Query)
+	for i := range found {
+		found[i].Snippet = highlight(found[i].Message.Content, terms)
+	}
+
+	results.Results = found
+	results.Total = total
+	return results, nil
+}
+
+// searchTerms splits a search query into the words that should be highlighted,
+// negated words are skipped since they can not be part of a result
+func searchTerms(query string) []string {
+	terms := []string{}
+	for _, field := range strings.Fields(query) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		term := strings.TrimFunc(field, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+		if len(term) > 0 {
+			terms = append(terms, strings.ToLower(term))
+		}
+	}
+	return terms
+}
+
+// highlight cuts the content down to the area around the first matching term
+// and marks every occurrence of a term in bold
+func highlight(content string, terms []string) string {
+	runes := []rune(content)
+	lower := []rune(strings.ToLower(content))
+
+	// the lower case version has to line up with the original content
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	// find all matches as rune ranges
+	type match struct{ start, end int }
+	matches := []match{}
+	for i := 0; i < len(lower); i++ {
+		for _, term := range terms {
+			termRunes := []rune(term)
+			end := i + len(termRunes)
+			if end <= len(lower) && string(lower[i:end]) == term {
+				matches = append(matches, match{i, end})
+				i = end - 1
+				break
+			}
+		}
+	}
+
+	start, end := 0, len(runes)
+	if len(matches) > 0 {
+		start = max(matches[0].start-SNIPPET_RADIUS, 0)
+		end = min(matches[0].end+SNIPPET_RADIUS, len(runes))
+	} else {
+		end = min(2*SNIPPET_RADIUS, len(runes))
+	}
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("…")
+	}
+
+	position := start
+	for _, m := range matches {
+		if m.start < start || m.end > end {
+			continue
+		}
+		snippet.WriteString(string(runes[position:m.start]))
+		snippet.WriteString("**" + string(runes[m.start:m.end]) + "**")
+		position = m.end
+	}
+	snippet.WriteString(string(runes[position:end]))
+
+	if end < len(runes) {
+		snippet.WriteString("…")
+	}
+
+	return snippet.String()
+}
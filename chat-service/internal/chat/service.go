@@ -1,8 +1,6 @@
 package chat
 
 import (
-	"context"
-	"fmt"
 	"net/http"
 	"slices"
 	"strings"
@@ -12,36 +10,73 @@ import (
 	"github.com/nilspolek/DevOps/Chat/internal/utils"
 )
 
-type Guess struct {
-	Word   string
-	UserId uuid.UUID
+const (
+	// upper bound for the byte length of a reaction, long enough for
+	// composed emojis like families or flags with skin tone modifiers
+	MAX_REACTION_LENGTH = 32
+	DEFAULT_MAX_PINS    = 50
+)
+
+var (
+	logger = utils.GetLogger("chat")
+)
+
+type ChatService struct {
+	storage      utils.Storage
+	auth         utils.AuthService
+	ai           utils.AiService
+	maxPins      int
+	inviteSecret []byte
+	commands     map[string]Command
+	unfurler     utils.Unfurler
 }
 
-type GuessingGame struct {
-	words       []string
-	gussedWords map[string]struct{}
-	chatId      uuid.UUID
-	guesses     []Guess
+// Option configures optional settings of the chat service
+type Option func(*ChatService)
+
+// WithMaxPins limits the number of messages that can be pinned in a chat
+func WithMaxPins(maxPins int) Option {
+	return func(c *ChatService) {
+		if maxPins > 0 {
+			c.maxPins = maxPins
+		}
+	}
 }
 
-type ChatService struct {
-	storage       utils.Storage
-	auth          utils.AuthService
-	ai            utils.AiService
-	guessingNames map[uuid.UUID]GuessingGame
+// WithInviteSecret sets the key invite tokens are signed with, all replicas have to use the same secret
+func WithInviteSecret(secret string) Option {
+	return func(c *ChatService) {
+		if len(secret) > 0 {
+			c.inviteSecret = []byte(secret)
+		}
+	}
 }
 
-func New(storage utils.Storage, auth utils.AuthService, ai utils.AiService) ChatService {
-	return ChatService{
-		storage:       storage,
-		auth:          auth,
-		ai:            ai,
-		guessingNames: make(map[uuid.UUID]GuessingGame), // map chat id to guessing game, initially empty
+func New(storage utils.Storage, auth utils.AuthService, ai utils.AiService, opts ...Option) ChatService {
+	service := ChatService{
+		storage:      storage,
+		auth:         auth,
+		ai:           ai,
+		maxPins:      DEFAULT_MAX_PINS,
+		inviteSecret: randomSecret(),
+		commands:     make(map[string]Command),
+	}
+
+	// built-in commands are registered first, so they can not be replaced
+	service.RegisterCommand(helpCommand{})
+	service.RegisterCommand(guessCommand{})
+	service.RegisterCommand(quizCommand{})
+	service.RegisterCommand(answerCommand{})
+
+	for _, opt := range opts {
+		opt(&service)
 	}
+
+	return service
 }
 
-func (c *ChatService) GetChats(user uuid.UUID) ([]utils.Chat, error) {
-	chats, err := c.storage.GetChats(user)
+func (c *ChatService) GetChats(user uuid.UUID, opts utils.ChatListOptions) ([]utils.Chat, error) {
+	chats, err := c.storage.GetChats(user, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +95,7 @@ func (c *ChatService) GetChats(user uuid.UUID) ([]utils.Chat, error) {
 		})
 	}
 
-	return chats, err
+	return c.applyChatSettings(user, chats, opts)
 }
 
 func (c *ChatService) GetMessages(userId uuid.UUID, chatId uuid.UUID, limit, offset int) ([]utils.Message, error) {
@@ -80,8 +115,22 @@ func (c *ChatService) MemberOfChat(userId uuid.UUID, chatId uuid.UUID) bool {
 // the AI is not a user and has therefore not a user id
 // to account for the missing user id the chat id with the ai will just be the targeted user id
 func (c *ChatService) AnswerAiChat(userId uuid.UUID, content string) (*utils.Message, error) {
+	message := utils.Message{
+		ID:        uuid.New(),
+		ChatID:    userId,
+		SenderID:  userId,
+		Timestamp: time.Now(),
+		UpdatedAt: time.Now(),
+		Content:   content,
+		Kind:      utils.KIND_TEXT,
+	}
+	err := c.validateMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
 	// ensure that the chat exists
-	_, err := c.storage.GetChat(userId)
+	_, err = c.storage.GetChat(userId)
 
 	// chat does not exist yet => we have to create a new one
 	if err != nil {
@@ -124,38 +173,38 @@ func (c *ChatService) AnswerAiChat(userId uuid.UUID, content string) (*utils.Mes
 			Timestamp: time.Now(),
 			UpdatedAt: time.Now(),
 			Read:      true,
+			Kind:      utils.KIND_AI,
+			Payload:   &utils.MessagePayload{AI: &utils.AIPayload{}},
+		}
+
+		// the answer is stored before it is streamed, the chunks only update its content
+		err := c.validateMessage(msg)
+		if err == nil {
+			err = c.storage.SaveMessage(msg)
+		}
+		if err != nil {
+			logger.Err(err).Str("chat", userId.String()).Msg("failed to store the AI answer")
+			return
 		}
 
 		// right now the ask ai is context unaware this is super shit, we definitly have to change that
-		err := c.ai.AskAI(context+"User: "+content, func(response utils.GenerateResponse) {
+		err = c.ai.AskAI(context+"User: "+content, func(response utils.GenerateResponse) {
 			msg.Content = msg.Content + response.Response
 			msg.UpdatedAt = time.Now()
 			c.storage.UpdateMessage(msg)
 		})
 
+		// the streamed answer is replaced, so clients do not wait for a partial answer
 		if err != nil || msg.Content == "" {
-			msg = utils.Message{
-				ChatID:    userId,
-				Content:   "Sorry, something went wrong.",
-				ID:        uuid.New(),
-				SenderID:  uuid.MustParse(utils.AIChat),
-				Timestamp: time.Now(),
-				UpdatedAt: time.Now(),
-				Read:      true,
-			}
+			msg.Content = "Sorry, something went wrong."
+			msg.Payload.AI.Failed = true
 		}
-		c.storage.SaveMessage(msg)
+		msg.Payload.AI.Complete = true
+		msg.UpdatedAt = time.Now()
+		c.storage.UpdateMessage(msg)
 
 	}()
 
-	message := utils.Message{
-		ID:        uuid.New(),
-		ChatID:    userId,
-		SenderID:  userId,
-		Timestamp: time.Now(),
-		UpdatedAt: time.Now(),
-		Content:   content,
-	}
 	err = c.storage.SaveMessage(message)
 	if err != nil {
 		return nil, err
@@ -165,217 +214,35 @@ func (c *ChatService) AnswerAiChat(userId uuid.UUID, content string) (*utils.Mes
 	return &message, err
 }
 
-func (c *ChatService) Command(userId uuid.UUID, chatId uuid.UUID, content, command string) (*utils.Message, error) {
-	if len(content) == 0 {
-		return nil, utils.NewError("message content is empty", http.StatusBadRequest)
-	}
-
-	// handle ai chat
-	if chatId.String() == userId.String() {
-		return nil, utils.NewError("command not supported for AI chat", http.StatusBadRequest)
-	}
-
-	var asyncFunction func() = nil
-
-	switch command {
-	case "guess":
-		asyncFunction = func() {
-
-			time.Sleep(100 * time.Millisecond)
-
-			game, exists := c.guessingNames[chatId]
-			if !exists {
-
-				startGameMsg := utils.Message{
-					ID:        uuid.New(),
-					ChatID:    chatId,
-					SenderID:  uuid.MustParse(utils.AIChat),
-					Timestamp: time.Now(),
-					UpdatedAt: time.Now(),
-					Content:   "Starting a new game. Please wait a moment...",
-				}
-				c.storage.SaveMessage(startGameMsg)
-
-				// check if the chat is already in the guessing game
-				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-
-				go func() {
-					<-ctx.Done()
-					game, ok := c.guessingNames[chatId]
-					if !ok {
-						cancel()
-						return
-					}
-
-					// send the message that the user has guessed all words
-					// Count guesses per user
-					userGuesses := make(map[uuid.UUID]int)
-					for _, guess := range game.guesses {
-						userGuesses[guess.UserId]++
-					}
-
-					// Create leaderboard text
-					leaderboard := "# Final Leaderboard:\n\n"
-					for userId, count := range userGuesses {
-						leaderboard += fmt.Sprintf("User @%s: %d words\n\n", userId.String(), count)
-					}
-
-					if len(game.guesses) == 0 {
-						leaderboard = "No one guessed any words."
-					}
-
-					// send message that the game has ended
-					message := utils.Message{
-						ID:        uuid.New(),
-						ChatID:    chatId,
-						SenderID:  uuid.MustParse(utils.AIChat),
-						Timestamp: time.Now(),
-						UpdatedAt: time.Now(),
-						Content:   fmt.Sprintf("# Game Over! ⌛\n\nThe guessing game has ended. No one guessed all words in time.\n\n**The words were:** %v \n\n%v", strings.Join(c.guessingNames[chatId].words, ", "), leaderboard),
-					}
-					c.storage.SaveMessage(message)
-					delete(c.guessingNames, chatId)
-					cancel()
-				}()
-
-				// create a new guessing game
-				guesses, err := c.ai.GuessWords(content)
-				if err != nil {
-					message := utils.Message{
-						ID:        uuid.New(),
-						ChatID:    chatId,
-						SenderID:  uuid.MustParse(utils.AIChat),
-						Timestamp: time.Now(),
-						UpdatedAt: time.Now(),
-						Content:   "Failed to generate guesses for that topic. Please try again.",
-					}
-					c.storage.SaveMessage(message)
-					return
-				}
-
-				c.guessingNames[chatId] = GuessingGame{
-					words:       guesses,
-					chatId:      chatId,
-					guesses:     []Guess{},
-					gussedWords: map[string]struct{}{},
-				}
-
-				// send a message that a new game has started
-				message := utils.Message{
-					ID:        uuid.New(),
-					ChatID:    chatId,
-					SenderID:  uuid.MustParse(utils.AIChat),
-					Timestamp: time.Now(),
-					UpdatedAt: time.Now(),
-					Content:   fmt.Sprintf("# A new guessing game has started about **%s**. \nThere are **%v** words to guess.\n\nUse: `/guess [word]` - to guess the words.\n\nGood luck guessing!", content, len(guesses)),
-				}
-				c.storage.SaveMessage(message)
-
-				return
-			}
-
-			// check if the guessed word is correct
-			for _, word := range game.words {
-				word = strings.ToLower(word)
-				content = strings.ToLower(content)
-				// check if the word has not been guessed yet and if the word is correct
-				if _, exists := game.gussedWords[word]; !exists && word == content {
-					// add the guess to the list of guesses
-					game.guesses = append(game.guesses, Guess{
-						Word:   content,
-						UserId: userId,
-					})
-
-					c.guessingNames[chatId] = game
-
-					// check if the user has guessed all words
-					if len(game.guesses) == len(game.words) {
-						// send the message that the user has guessed all words
-						// Count guesses per user
-						userGuesses := make(map[uuid.UUID]int)
-						for _, guess := range game.guesses {
-							userGuesses[guess.UserId]++
-						}
-
-						// Create leaderboard text
-						leaderboard := "🏆 Game Over! Final Leaderboard:\n\n"
-						for userId, count := range userGuesses {
-							leaderboard += fmt.Sprintf("User @%s: %d words\n", userId.String(), count)
-						}
-
-						message := utils.Message{
-							ID:        uuid.New(),
-							ChatID:    chatId,
-							SenderID:  uuid.MustParse(utils.AIChat),
-							Timestamp: time.Now(),
-							UpdatedAt: time.Now(),
-							Content:   leaderboard + "\nCongratulations, all words have been guessed!",
-						}
-
-						// remove the game from the guessing games
-						delete(c.guessingNames, chatId)
-						c.storage.SaveMessage(message)
-						return
-					}
-
-					// send the message that the user has guessed the word
-					message := utils.Message{
-						ID:        uuid.New(),
-						ChatID:    chatId,
-						SenderID:  uuid.MustParse(utils.AIChat),
-						Timestamp: time.Now(),
-						UpdatedAt: time.Now(),
-						Content:   fmt.Sprintf("Congratulations, you have guessed one of the words. \n\nThere are still **%d** words left.", len(game.words)-len(game.guesses)),
-					}
-					c.storage.SaveMessage(message)
-					return
-				}
-			}
-
-			// send the message that the user has guessed the wrong word
-			message := utils.Message{
-				ID:        uuid.New(),
-				ChatID:    chatId,
-				SenderID:  uuid.MustParse(utils.AIChat),
-				Timestamp: time.Now(),
-				UpdatedAt: time.Now(),
-				Content:   "Sorry, that is not a correct word. Please try again.",
-			}
-			c.storage.SaveMessage(message)
-
-		}
-	default:
-		return nil, utils.NewError("command not supported", http.StatusBadRequest)
+// replyThread checks that the message replied to is part of the chat and returns the thread the reply belongs to
+func (c *ChatService) replyThread(chatId uuid.UUID, replyTo *uuid.UUID) (*uuid.UUID, error) {
+	if replyTo == nil {
+		return nil, nil
 	}
 
-	// check if the user is part of that chat
-	member := c.MemberOfChat(userId, chatId)
-	if !member {
-		return nil, utils.NewError("User is not a member of the chat", http.StatusUnauthorized)
+	parent, err := c.storage.GetMessage(*replyTo)
+	if err != nil {
+		return nil, utils.NewError("reply message not found", http.StatusNotFound)
 	}
 
-	message := utils.Message{
-		ID:        uuid.New(),
-		ChatID:    chatId,
-		SenderID:  userId,
-		Timestamp: time.Now(),
-		UpdatedAt: time.Now(),
-		Content:   content,
-		Command:   command,
+	if parent.ChatID.String() != chatId.String() {
+		return nil, utils.NewError("reply message is not part of this chat", http.StatusBadRequest)
 	}
 
-	err := c.storage.SaveMessage(message)
-	if err != nil {
-		return nil, err
+	// replies to replies belong to the thread of the root message
+	if parent.ThreadID != nil {
+		return parent.ThreadID, nil
 	}
-
-	go asyncFunction()
-
-	err = c.storage.UpdateChatActivity(chatId)
-	return &message, err
+	return &parent.ID, nil
 }
 
 func (c *ChatService) SendMessage(userId uuid.UUID, chatId uuid.UUID, content string, media []uuid.UUID, replyTo *uuid.UUID) (*utils.Message, error) {
+	return c.sendMessage(uuid.New(), userId, chatId, content, media, replyTo, nil)
+}
+
+// sendMessage sends a message with a given id, scheduled messages keep their id when they are sent.
+// Forwarded copies reference the original message
+func (c *ChatService) sendMessage(id uuid.UUID, userId uuid.UUID, chatId uuid.UUID, content string, media []uuid.UUID, replyTo *uuid.UUID, forwardedFrom *utils.ForwardedFrom) (*utils.Message, error) {
 
 	if len(content) == 0 && len(media) == 0 {
 		return nil, utils.NewError("message content is empty", http.StatusBadRequest)
@@ -386,22 +253,19 @@ func (c *ChatService) SendMessage(userId uuid.UUID, chatId uuid.UUID, content st
 		return c.AnswerAiChat(userId, content)
 	}
 
-	// check if the message exists if there is a reply
-	if replyTo != nil {
-		_, err := c.storage.GetMessage(*replyTo)
-		if err != nil {
-			return nil, utils.NewError("reply message not found", http.StatusNotFound)
-		}
-	}
-
-	// check if the user is part of that chat
+	// check if the user is part of that chat, before anything about the replied message is revealed
 	member := c.MemberOfChat(userId, chatId)
 	if !member {
 		return nil, utils.NewError("User is not a member of the chat", http.StatusUnauthorized)
 	}
 
+	threadId, err := c.replyThread(chatId, replyTo)
+	if err != nil {
+		return nil, err
+	}
+
 	message := utils.Message{
-		ID:        uuid.New(),
+		ID:        id,
 		ChatID:    chatId,
 		SenderID:  userId,
 		Timestamp: time.Now(),
@@ -409,13 +273,34 @@ func (c *ChatService) SendMessage(userId uuid.UUID, chatId uuid.UUID, content st
 		Media:     media,
 		Content:   content,
 		ReplyTo:   replyTo,
+		ThreadID:  threadId,
+
+		ForwardedFrom: forwardedFrom,
+
+		Kind: messageKind(media),
 	}
 
-	err := c.storage.SaveMessage(message)
+	err = c.validateMessage(message)
 	if err != nil {
 		return nil, err
 	}
 
+	err = c.storage.SaveMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.unfurler != nil {
+		go c.unfurlLinks(message.ID, message.Content, false)
+	}
+
+	if threadId != nil {
+		err = c.storage.AddThreadReply(*threadId, message.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err = c.storage.UpdateChatActivity(chatId)
 	return &message, err
 }
@@ -431,6 +316,11 @@ func (c *ChatService) DeleteChat(userId uuid.UUID, chatId uuid.UUID) error {
 		return utils.NewError("Updater is not present in members list of chat.", http.StatusBadRequest)
 	}
 
+	// direct chats have no hierarchy, both members may delete them. The AI chat has the id of its only member
+	if previousChat.Name != "Direct Chat" && chatId != userId && !previousChat.HasRole(userId, utils.ROLE_OWNER) {
+		return utils.NewError("Only the owner can delete the chat", http.StatusForbidden)
+	}
+
 	return c.storage.DeleteChat(chatId)
 }
 
@@ -444,12 +334,110 @@ func (c *ChatService) UpdateMessage(userId uuid.UUID, message utils.Message) (ut
 		return utils.Message{}, utils.NewError("Not the sender of the message", http.StatusUnauthorized)
 	}
 
-	original.Content = message.Content
-	original.UpdatedAt = time.Now()
+	if original.Deleted {
+		return utils.Message{}, utils.NewError("cannot edit a deleted message", http.StatusBadRequest)
+	}
+
+	// the tallies are counted on the message and would be overwritten
+	if original.Poll != nil {
+		return utils.Message{}, utils.NewError("cannot edit a poll", http.StatusBadRequest)
+	}
+
+	// the command already ran with the original arguments
+	if original.Kind == utils.KIND_COMMAND {
+		return utils.Message{}, utils.NewError("cannot edit a command", http.StatusBadRequest)
+	}
+
+	media := original.Media
 	if len(message.Media) > 0 {
-		original.Media = message.Media
+		media = message.Media
 	}
-	return original, c.storage.UpdateMessage(original)
+
+	// nothing to store if the message did not change
+	if original.Content == message.Content && slices.Equal(original.Media, media) {
+		return original, nil
+	}
+
+	// keep the version that is replaced
+	now := time.Now()
+	revision := utils.Revision{
+		ID:        uuid.New(),
+		MessageID: original.ID,
+		Content:   original.Content,
+		Media:     original.Media,
+		Timestamp: original.Timestamp,
+		EditedAt:  now,
+	}
+	if original.EditedAt != nil {
+		revision.Timestamp = *original.EditedAt
+	}
+
+	err = c.storage.SaveRevision(revision)
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	// the previews of the old content are replaced once the new content is unfurled
+	stale := len(original.Previews) > 0
+	contentChanged := original.Content != message.Content
+
+	original.Content = message.Content
+	original.Media = media
+	original.Edited = true
+	original.EditCount++
+	original.EditedAt = &now
+	original.UpdatedAt = now
+	if contentChanged {
+		original.Previews = nil
+	}
+
+	// adding or removing media changes the kind of the message, messages without a kind are old text messages
+	if original.Kind == "" || original.Kind == utils.KIND_TEXT || original.Kind == utils.KIND_MEDIA {
+		original.Kind = messageKind(media)
+	}
+
+	err = c.validateMessage(original)
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	err = c.storage.UpdateMessage(original)
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	if c.unfurler != nil && contentChanged {
+		go c.unfurlLinks(original.ID, original.Content, stale)
+	}
+	return original, nil
+}
+
+// GetHistory returns the message with all previous versions, the user has to be a member of the chat
+func (c *ChatService) GetHistory(userId uuid.UUID, messageId uuid.UUID) (*utils.MessageHistory, error) {
+	message, err := c.storage.GetMessage(messageId)
+	if err != nil {
+		return nil, utils.NewError("message not found", http.StatusNotFound)
+	}
+
+	err = c.storage.MemberOfChat(userId, message.ChatID)
+	if err != nil {
+		return nil, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	// the content of deleted messages is hidden, that includes earlier versions
+	if message.Deleted {
+		return nil, utils.NewError("message has been deleted", http.StatusGone)
+	}
+
+	revisions, err := c.storage.GetRevisions(messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.MessageHistory{
+		Message:   message,
+		Revisions: revisions,
+	}, nil
 }
 
 func (c *ChatService) ReadMessage(userId uuid.UUID, messageId uuid.UUID) (utils.Message, error) {
@@ -467,9 +455,177 @@ func (c *ChatService) ReadMessage(userId uuid.UUID, messageId uuid.UUID) (utils.
 		return utils.Message{}, utils.NewError("cannot read own message", http.StatusBadRequest)
 	}
 
-	message.Read = true
-	message.UpdatedAt = time.Now()
-	return message, c.storage.UpdateMessage(message)
+	// nothing changes if the user has already read the message
+	if _, read := message.ReadAt(userId); read {
+		return message, nil
+	}
+
+	err = c.storage.MarkMessageRead(messageId, userId, time.Now())
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	return c.storage.GetMessage(messageId)
+}
+
+// MarkChatRead marks every message of the chat up to the given message or time as read by the user.
+// Other members are notified with a single chat_read event instead of an update per message
+func (c *ChatService) MarkChatRead(userId uuid.UUID, chatId uuid.UUID, messageId *uuid.UUID, timestamp *time.Time) (utils.Event, error) {
+	err := c.storage.MemberOfChat(userId, chatId)
+	if err != nil {
+		return utils.Event{}, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	upTo := time.Now()
+	if messageId != nil {
+		message, err := c.storage.GetMessage(*messageId)
+		if err != nil || message.ChatID.String() != chatId.String() {
+			return utils.Event{}, utils.NewError("message not found", http.StatusNotFound)
+		}
+		upTo = message.Timestamp
+	} else if timestamp != nil {
+		upTo = *timestamp
+	}
+
+	event := utils.Event{
+		ID:        uuid.New(),
+		Type:      utils.EVENT_CHAT_READ,
+		ChatID:    chatId,
+		UserID:    userId,
+		Timestamp: time.Now(),
+		ReadUpTo:  &upTo,
+	}
+
+	event.Count, err = c.storage.MarkChatRead(chatId, userId, upTo, event.Timestamp)
+	if err != nil {
+		return utils.Event{}, err
+	}
+
+	// no need to notify anyone if nothing changed
+	if event.Count == 0 {
+		return event, nil
+	}
+
+	return event, c.storage.SaveEvent(event)
+}
+
+// GetReceipts returns the read state of the message for every member of the chat except the sender
+func (c *ChatService) GetReceipts(userId uuid.UUID, messageId uuid.UUID) ([]utils.Receipt, error) {
+	message, err := c.storage.GetMessage(messageId)
+	if err != nil {
+		return nil, utils.NewError("message not found", http.StatusNotFound)
+	}
+
+	chat, err := c.storage.GetChat(message.ChatID)
+	if err != nil {
+		return nil, utils.NewError("chat not found", http.StatusNotFound)
+	}
+
+	if !slices.ContainsFunc(chat.Members, func(i uuid.UUID) bool { return userId.String() == i.String() }) {
+		return nil, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	receipts := []utils.Receipt{}
+	for _, member := range chat.Members {
+		if member.String() == message.SenderID.String() {
+			continue
+		}
+
+		readAt, read := message.ReadAt(member)
+		receipts = append(receipts, utils.Receipt{
+			UserID: member,
+			Read:   read,
+			ReadAt: readAt,
+		})
+	}
+
+	return receipts, nil
+}
+
+// GetThread returns the root message of the thread the message belongs to,
+// followed by all replies in chronological order
+func (c *ChatService) GetThread(userId uuid.UUID, messageId uuid.UUID) ([]utils.Message, error) {
+	message, err := c.storage.GetMessage(messageId)
+	if err != nil {
+		return nil, utils.NewError("message not found", http.StatusNotFound)
+	}
+
+	err = c.storage.MemberOfChat(userId, message.ChatID)
+	if err != nil {
+		return nil, utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	root := message
+	if message.ThreadID != nil {
+		root, err = c.storage.GetMessage(*message.ThreadID)
+		if err != nil {
+			return nil, utils.NewError("thread not found", http.StatusNotFound)
+		}
+	}
+
+	replies, err := c.storage.GetThread(root.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]utils.Message{root}, replies...), nil
+}
+
+func (c *ChatService) ReactToMessage(userId uuid.UUID, messageId uuid.UUID, emoji string) (utils.Message, error) {
+	emoji, err := c.checkReaction(userId, messageId, emoji)
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	err = c.storage.AddReaction(messageId, utils.Reaction{
+		Emoji:     emoji,
+		UserID:    userId,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	return c.storage.GetMessage(messageId)
+}
+
+func (c *ChatService) RemoveReaction(userId uuid.UUID, messageId uuid.UUID, emoji string) (utils.Message, error) {
+	emoji, err := c.checkReaction(userId, messageId, emoji)
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	err = c.storage.RemoveReaction(messageId, userId, emoji)
+	if err != nil {
+		return utils.Message{}, err
+	}
+
+	return c.storage.GetMessage(messageId)
+}
+
+// checkReaction validates the emoji and ensures that the user is allowed to react to the message.
+// it returns the normalized emoji
+func (c *ChatService) checkReaction(userId uuid.UUID, messageId uuid.UUID, emoji string) (string, error) {
+	emoji = strings.TrimSpace(emoji)
+	if len(emoji) == 0 || len(emoji) > MAX_REACTION_LENGTH || strings.ContainsAny(emoji, " \t\n") {
+		return "", utils.NewError("invalid reaction", http.StatusBadRequest)
+	}
+
+	message, err := c.storage.GetMessage(messageId)
+	if err != nil {
+		return "", utils.NewError("message not found", http.StatusNotFound)
+	}
+
+	err = c.storage.MemberOfChat(userId, message.ChatID)
+	if err != nil {
+		return "", utils.NewError("not a member of that chat", http.StatusForbidden)
+	}
+
+	if message.Deleted {
+		return "", utils.NewError("cannot react to a deleted message", http.StatusBadRequest)
+	}
+
+	return emoji, nil
 }
 
 func (c *ChatService) DeleteMessage(userId uuid.UUID, messageId uuid.UUID) (utils.Message, error) {
@@ -483,6 +639,8 @@ func (c *ChatService) DeleteMessage(userId uuid.UUID, messageId uuid.UUID) (util
 	}
 
 	message.Content = ""
+	message.Previews = nil
+	message.Poll = nil
 	message.Deleted = true
 	message.UpdatedAt = time.Now()
 	return message, c.storage.DeleteMessage(messageId)
@@ -499,6 +657,10 @@ func (c *ChatService) UpdateChat(userId uuid.UUID, chatId uuid.UUID, name string
 		return nil, utils.NewError("Updater is not present in members list of chat.", http.StatusBadRequest)
 	}
 
+	if !previousChat.HasRole(userId, utils.ROLE_ADMIN) {
+		return nil, utils.NewError("Only admins can update the chat", http.StatusForbidden)
+	}
+
 	if name == "AI" || name == "Direct Chat" {
 		return nil, utils.NewError("invalid name", http.StatusBadRequest)
 	}
@@ -528,15 +690,36 @@ func (c *ChatService) UpdateChat(userId uuid.UUID, chatId uuid.UUID, name string
 		return nil, utils.NewError("One or more members do not exist", http.StatusBadRequest)
 	}
 
-	chat := utils.Chat{
-		ID:         chatId,
-		Name:       name,
-		Members:    uniqueMember,
-		CreatedAt:  time.Now(),
-		CreatorID:  userId,
-		LastActive: time.Now(),
+	// admins and the owner can only be removed by the owner
+	roles := map[string]utils.ChatRole{}
+	for _, member := range previousChat.Members {
+		role := previousChat.RoleOf(member)
+		if _, stays := membersMap[member]; stays {
+			roles[member.String()] = role
+			continue
+		}
+
+		if role == utils.ROLE_OWNER {
+			return nil, utils.NewError("The owner can not be removed from the chat", http.StatusForbidden)
+		}
+		if role == utils.ROLE_ADMIN && !previousChat.HasRole(userId, utils.ROLE_OWNER) {
+			return nil, utils.NewError("Only the owner can remove admins", http.StatusForbidden)
+		}
+	}
+
+	// new members get an explicit role, a creator that left is not the owner again when re-added
+	for _, member := range uniqueMember {
+		if _, ok := roles[member.String()]; !ok {
+			roles[member.String()] = utils.ROLE_MEMBER
+		}
 	}
 
+	chat := *previousChat
+	chat.Name = name
+	chat.Members = uniqueMember
+	chat.Roles = roles
+	chat.LastActive = time.Now()
+
 	err = c.storage.CreateOrUpdateChat(chat)
 	return &chat, err
 }
@@ -581,12 +764,39 @@ func (c *ChatService) CreateChat(userId uuid.UUID, name string, members []uuid.U
 		CreatedAt:  time.Now(),
 		CreatorID:  userId,
 		LastActive: time.Now(),
+		Roles:      map[string]utils.ChatRole{userId.String(): utils.ROLE_OWNER},
 	}
 
 	err := c.storage.CreateOrUpdateChat(chat)
 	return &chat, err
 }
 
+// postSystemMessage posts a message about an event in the chat, like members joining or leaving
+func (c *ChatService) postSystemMessage(chatId uuid.UUID, content string, payload utils.SystemPayload) error {
+	message := utils.Message{
+		ID:        uuid.New(),
+		ChatID:    chatId,
+		SenderID:  uuid.MustParse(utils.AIChat),
+		Timestamp: time.Now(),
+		UpdatedAt: time.Now(),
+		Content:   content,
+		Kind:      utils.KIND_SYSTEM,
+		Payload:   &utils.MessagePayload{System: &payload},
+	}
+
+	err := c.validateMessage(message)
+	if err != nil {
+		return err
+	}
+
+	err = c.storage.SaveMessage(message)
+	if err != nil {
+		return err
+	}
+
+	return c.storage.UpdateChatActivity(chatId)
+}
+
 func (c *ChatService) GetChat(id uuid.UUID) (*utils.Chat, error) {
 	chat, err := c.storage.GetChat(id)
 	if err != nil {
@@ -614,12 +824,7 @@ func (c *ChatService) CreateDirectChat(userId uuid.UUID, receiver uuid.UUID, ini
 		LastActive: time.Now(),
 	}
 
-	err = c.storage.CreateOrUpdateChat(chat)
-
-	if err != nil {
-		return nil, err
-	}
-
+	// the initial message is checked before the chat is created
 	if initialMessage != nil && len(*initialMessage) > 0 {
 		message := utils.Message{
 			ID:        uuid.New(),
@@ -628,9 +833,23 @@ func (c *ChatService) CreateDirectChat(userId uuid.UUID, receiver uuid.UUID, ini
 			Timestamp: time.Now(),
 			UpdatedAt: time.Now(),
 			Content:   *initialMessage,
+			Kind:      utils.KIND_TEXT,
+		}
+
+		err = c.validateMessage(message)
+		if err != nil {
+			return nil, err
 		}
 		chat.Messages = []utils.Message{message}
+	}
+
+	err = c.storage.CreateOrUpdateChat(chat)
+
+	if err != nil {
+		return nil, err
+	}
 
+	for _, message := range chat.Messages {
 		err = c.storage.SaveMessage(message)
 		if err != nil {
 			return nil, err
@@ -1,7 +1,11 @@
 package chat
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,6 +13,7 @@ import (
 	"github.com/nilspolek/DevOps/Chat/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -17,8 +22,8 @@ type MockStorage struct {
 	mock.Mock
 }
 
-func (m *MockStorage) GetChats(user uuid.UUID) ([]utils.Chat, error) {
-	args := m.Called(user)
+func (m *MockStorage) GetChats(user uuid.UUID, opts utils.ChatListOptions) ([]utils.Chat, error) {
+	args := m.Called(user, opts)
 	return args.Get(0).([]utils.Chat), args.Error(1)
 }
 
@@ -53,7 +58,9 @@ func (m *MockStorage) UpdateChatActivity(chat uuid.UUID) error {
 }
 
 func (m *MockStorage) GetChat(id uuid.UUID) (*utils.Chat, error) {
-	return nil, nil
+	args := m.Called(id)
+	chat, _ := args.Get(0).(*utils.Chat)
+	return chat, args.Error(1)
 }
 
 func (m *MockStorage) DeleteChat(chat uuid.UUID) error {
@@ -71,7 +78,310 @@ func (m *MockStorage) DeleteMessage(message uuid.UUID) error {
 	return args.Error(0)
 }
 
+func (m *MockStorage) AddReaction(messageId uuid.UUID, reaction utils.Reaction) error {
+	args := m.Called(messageId, reaction)
+	return args.Error(0)
+}
+
+func (m *MockStorage) RemoveReaction(messageId uuid.UUID, userId uuid.UUID, emoji string) error {
+	args := m.Called(messageId, userId, emoji)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetThread(rootId uuid.UUID) ([]utils.Message, error) {
+	args := m.Called(rootId)
+	return args.Get(0).([]utils.Message), args.Error(1)
+}
+
+func (m *MockStorage) AddThreadReply(rootId uuid.UUID, timestamp time.Time) error {
+	args := m.Called(rootId, timestamp)
+	return args.Error(0)
+}
+
+func (m *MockStorage) MarkMessageRead(messageId uuid.UUID, userId uuid.UUID, readAt time.Time) error {
+	args := m.Called(messageId, userId, readAt)
+	return args.Error(0)
+}
+
+func (m *MockStorage) MarkChatRead(chatId uuid.UUID, userId uuid.UUID, upTo time.Time, readAt time.Time) (int64, error) {
+	args := m.Called(chatId, userId, upTo, readAt)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStorage) SaveEvent(event utils.Event) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetChatIDs(user uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(user)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockStorage) SearchMessages(query utils.SearchQuery) ([]utils.SearchResult, int64, error) {
+	args := m.Called(query)
+	return args.Get(0).([]utils.SearchResult), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockStorage) GetChatMessagesFrom(chatId uuid.UUID, cursor utils.MessageCursor, older bool, inclusive bool, limit int) ([]utils.Message, error) {
+	args := m.Called(chatId, cursor, older, inclusive, limit)
+	return args.Get(0).([]utils.Message), args.Error(1)
+}
+
+func (m *MockStorage) SaveRevision(revision utils.Revision) error {
+	args := m.Called(revision)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetRevisions(messageId uuid.UUID) ([]utils.Revision, error) {
+	args := m.Called(messageId)
+	return args.Get(0).([]utils.Revision), args.Error(1)
+}
+
+func (m *MockStorage) SetChatRole(chatId uuid.UUID, userId uuid.UUID, role utils.ChatRole) error {
+	args := m.Called(chatId, userId, role)
+	return args.Error(0)
+}
+
+func (m *MockStorage) AddChatMembers(chatId uuid.UUID, members []uuid.UUID) error {
+	args := m.Called(chatId, members)
+	return args.Error(0)
+}
+
+func (m *MockStorage) RemoveChatMember(chatId uuid.UUID, userId uuid.UUID) error {
+	args := m.Called(chatId, userId)
+	return args.Error(0)
+}
+
+func (m *MockStorage) AddPin(chatId uuid.UUID, pin utils.Pin, maxPins int) (bool, error) {
+	args := m.Called(chatId, pin, maxPins)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) RemovePin(chatId uuid.UUID, messageId uuid.UUID) (bool, error) {
+	args := m.Called(chatId, messageId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) SaveScheduledMessage(message utils.ScheduledMessage) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetScheduledMessage(id uuid.UUID) (*utils.ScheduledMessage, error) {
+	args := m.Called(id)
+	message, _ := args.Get(0).(*utils.ScheduledMessage)
+	return message, args.Error(1)
+}
+
+func (m *MockStorage) GetScheduledMessages(userId uuid.UUID) ([]utils.ScheduledMessage, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]utils.ScheduledMessage), args.Error(1)
+}
+
+func (m *MockStorage) UpdateScheduledMessage(message utils.ScheduledMessage) (bool, error) {
+	args := m.Called(message)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) DeleteScheduledMessage(id uuid.UUID, userId uuid.UUID) (bool, error) {
+	args := m.Called(id, userId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) ClaimScheduledMessage(now time.Time, lease time.Duration) (*utils.ScheduledMessage, error) {
+	args := m.Called(now, lease)
+	message, _ := args.Get(0).(*utils.ScheduledMessage)
+	return message, args.Error(1)
+}
+
+func (m *MockStorage) FinishScheduledMessage(id uuid.UUID, failure string) error {
+	args := m.Called(id, failure)
+	return args.Error(0)
+}
+
+func (m *MockStorage) SetChatRetention(chatId uuid.UUID, retention *utils.Retention) error {
+	args := m.Called(chatId, retention)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetChatsWithRetention() ([]utils.Chat, error) {
+	args := m.Called()
+	return args.Get(0).([]utils.Chat), args.Error(1)
+}
+
+func (m *MockStorage) ExpireMessages(chatId uuid.UUID, before time.Time) (int64, error) {
+	args := m.Called(chatId, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStorage) SaveInvite(invite utils.Invite) error {
+	args := m.Called(invite)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetInvite(id uuid.UUID) (*utils.Invite, error) {
+	args := m.Called(id)
+	invite, _ := args.Get(0).(*utils.Invite)
+	return invite, args.Error(1)
+}
+
+func (m *MockStorage) GetInvites(chatId uuid.UUID) ([]utils.Invite, error) {
+	args := m.Called(chatId)
+	return args.Get(0).([]utils.Invite), args.Error(1)
+}
+
+func (m *MockStorage) RevokeInvite(chatId uuid.UUID, inviteId uuid.UUID) (bool, error) {
+	args := m.Called(chatId, inviteId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) UseInvite(inviteId uuid.UUID, now time.Time) (bool, error) {
+	args := m.Called(inviteId, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) GetChatSettings(userId uuid.UUID) ([]utils.ChatSettings, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]utils.ChatSettings), args.Error(1)
+}
+
+func (m *MockStorage) SaveChatSettings(settings utils.ChatSettings) error {
+	args := m.Called(settings)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetActiveGame(chatId uuid.UUID) (*utils.Game, error) {
+	args := m.Called(chatId)
+	game, _ := args.Get(0).(*utils.Game)
+	return game, args.Error(1)
+}
+
+func (m *MockStorage) StartGame(game utils.Game) (bool, error) {
+	args := m.Called(game)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) SetGameWords(gameId uuid.UUID, words []string, deadline time.Time) (bool, error) {
+	args := m.Called(gameId, words, deadline)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) AddGameGuess(gameId uuid.UUID, guess utils.GameGuess) (*utils.Game, error) {
+	args := m.Called(gameId, guess)
+	game, _ := args.Get(0).(*utils.Game)
+	return game, args.Error(1)
+}
+
+func (m *MockStorage) FinishGame(gameId uuid.UUID, status utils.GameStatus, finishedAt time.Time) (bool, error) {
+	args := m.Called(gameId, status, finishedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) ClaimExpiredGame(now time.Time) (*utils.Game, error) {
+	args := m.Called(now)
+	game, _ := args.Get(0).(*utils.Game)
+	return game, args.Error(1)
+}
+
+func (m *MockStorage) AddGameHint(gameId uuid.UUID, wordIndex int) (*utils.Game, error) {
+	args := m.Called(gameId, wordIndex)
+	game, _ := args.Get(0).(*utils.Game)
+	return game, args.Error(1)
+}
+
+func (m *MockStorage) RecordGameScores(chatId uuid.UUID, scores []utils.GameScore) error {
+	args := m.Called(chatId, scores)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetGameLeaderboard(chatId uuid.UUID, limit int) ([]utils.GameScore, error) {
+	args := m.Called(chatId, limit)
+	return args.Get(0).([]utils.GameScore), args.Error(1)
+}
+
+func (m *MockStorage) ImportMessages(messages []utils.Message) (int64, error) {
+	args := m.Called(messages)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStorage) SetMessagePreviews(messageId uuid.UUID, content string, previews []utils.LinkPreview) (bool, error) {
+	args := m.Called(messageId, content, previews)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) SavePollVote(vote utils.PollVote) (bool, error) {
+	args := m.Called(vote)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) DeletePollVote(messageId uuid.UUID, userId uuid.UUID) error {
+	args := m.Called(messageId, userId)
+	return args.Error(0)
+}
+
+func (m *MockStorage) CountPollVote(vote utils.PollVote, anonymous bool) (bool, error) {
+	args := m.Called(vote, anonymous)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) ClosePoll(messageId uuid.UUID, closedAt time.Time) (bool, error) {
+	args := m.Called(messageId, closedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) GetActiveQuiz(chatId uuid.UUID) (*utils.Quiz, error) {
+	args := m.Called(chatId)
+	quiz, _ := args.Get(0).(*utils.Quiz)
+	return quiz, args.Error(1)
+}
+
+func (m *MockStorage) StartQuiz(quiz utils.Quiz) (bool, error) {
+	args := m.Called(quiz)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) SetQuizQuestions(quizId uuid.UUID, questions []utils.QuizQuestion, startedAt time.Time, deadline time.Time) (bool, error) {
+	args := m.Called(quizId, questions, startedAt, deadline)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) AddQuizAnswer(quizId uuid.UUID, answer utils.QuizAnswer) (bool, error) {
+	args := m.Called(quizId, answer)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) FinishQuiz(quizId uuid.UUID, status utils.QuizStatus, finishedAt time.Time) (bool, error) {
+	args := m.Called(quizId, status, finishedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) ClaimQuizQuestion(now time.Time, deadline time.Time) (*utils.Quiz, error) {
+	args := m.Called(now, deadline)
+	quiz, _ := args.Get(0).(*utils.Quiz)
+	return quiz, args.Error(1)
+}
+
 // Mock AuthService
+type MockAiService struct {
+	mock.Mock
+}
+
+func (m *MockAiService) AskAI(prompt string, response func(response utils.GenerateResponse)) error {
+	args := m.Called(prompt, response)
+	return args.Error(0)
+}
+
+func (m *MockAiService) GuessWords(topic string, count int, difficulty string) ([]string, error) {
+	args := m.Called(topic, count, difficulty)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockAiService) GenerateQuiz(topic string, count int) ([]utils.QuizQuestion, error) {
+	args := m.Called(topic, count)
+	return args.Get(0).([]utils.QuizQuestion), args.Error(1)
+}
+
 type MockAuthService struct {
 	mock.Mock
 }
@@ -89,6 +399,27 @@ func (m *MockAuthService) VerifyToken(token string) (*utils.User, error) {
 	return nil, nil
 }
 
+func (m *MockAuthService) GetUsersByEmail(emails ...string) (map[string]utils.User, error) {
+	args := m.Called(emails)
+	return args.Get(0).(map[string]utils.User), args.Error(1)
+}
+
+func (m *MockAuthService) GetUsers(ids ...uuid.UUID) (map[uuid.UUID]utils.User, error) {
+	args := m.Called(ids)
+	return args.Get(0).(map[uuid.UUID]utils.User), args.Error(1)
+}
+
+// Mock Unfurler
+type MockUnfurler struct {
+	mock.Mock
+}
+
+func (m *MockUnfurler) Unfurl(url string) (*utils.LinkPreview, error) {
+	args := m.Called(url)
+	preview, _ := args.Get(0).(*utils.LinkPreview)
+	return preview, args.Error(1)
+}
+
 func TestGetChats(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockAuth := new(MockAuthService)
@@ -110,9 +441,11 @@ func TestGetChats(t *testing.T) {
 			Name:     "AI",
 		}}
 
-	mockStorage.On("GetChats", userId).Return(expectedChats, nil)
+	opts := utils.ChatListOptions{IncludeMessages: true}
+	mockStorage.On("GetChats", userId, opts).Return(expectedChats, nil)
+	mockStorage.On("GetChatSettings", userId).Return([]utils.ChatSettings{}, nil)
 
-	chats, err := service.GetChats(userId)
+	chats, err := service.GetChats(userId, opts)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedChats, chats)
@@ -193,6 +526,24 @@ func TestCreateDirectChat(t *testing.T) {
 	mockAuth.AssertExpectations(t)
 }
 
+func TestCreateDirectChat_BlankInitialMessage(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	receiverId := uuid.New()
+	initialMessage := "   "
+
+	mockAuth.On("Exists", receiverId).Return(true, nil)
+
+	_, err := service.CreateDirectChat(uuid.New(), receiverId, &initialMessage)
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "CreateOrUpdateChat", mock.Anything)
+	mockStorage.AssertNotCalled(t, "SaveMessage", mock.Anything)
+}
+
 func TestCreateDirectChat_ReceiverDoesNotExist(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockAuth := new(MockAuthService)
@@ -266,6 +617,9 @@ func TestUpdateMessage_Success(t *testing.T) {
 	updatedContent := "updated"
 
 	mockStorage.On("GetMessage", messageId).Return(originalMessage, nil)
+	mockStorage.On("SaveRevision", mock.MatchedBy(func(r utils.Revision) bool {
+		return r.Content == "original" && r.MessageID == messageId
+	})).Return(nil)
 	mockStorage.On("UpdateMessage", mock.MatchedBy(func(m utils.Message) bool {
 		return m.Content == updatedContent && m.ID == messageId
 	})).Return(nil)
@@ -274,6 +628,68 @@ func TestUpdateMessage_Success(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.Equal(t, updatedContent, result.Content)
+	assert.True(t, result.Edited)
+	assert.Equal(t, 1, result.EditCount)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestUpdateMessage_Unchanged(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	messageId := uuid.New()
+	originalMessage := utils.Message{ID: messageId, SenderID: userId, Content: "original"}
+
+	mockStorage.On("GetMessage", messageId).Return(originalMessage, nil)
+
+	result, err := service.UpdateMessage(userId, utils.Message{ID: messageId, Content: "original"})
+
+	assert.NoError(t, err)
+	assert.False(t, result.Edited)
+	mockStorage.AssertNotCalled(t, "SaveRevision", mock.Anything)
+	mockStorage.AssertNotCalled(t, "UpdateMessage", mock.Anything)
+}
+
+func TestGetHistory(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	message := utils.Message{ID: uuid.New(), ChatID: chatId, Content: "v2", Edited: true, EditCount: 1}
+	revisions := []utils.Revision{{ID: uuid.New(), MessageID: message.ID, Content: "v1"}}
+
+	mockStorage.On("GetMessage", message.ID).Return(message, nil)
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("GetRevisions", message.ID).Return(revisions, nil)
+
+	history, err := service.GetHistory(userId, message.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, message, history.Message)
+	assert.Equal(t, revisions, history.Revisions)
+}
+
+func TestGetHistory_DeletedMessage(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	message := utils.Message{ID: uuid.New(), ChatID: chatId, Deleted: true}
+
+	mockStorage.On("GetMessage", message.ID).Return(message, nil)
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+
+	_, err := service.GetHistory(userId, message.ID)
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusGone, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "GetRevisions", mock.Anything)
 }
 
 func TestUpdateMessage_NotSender(t *testing.T) {
@@ -321,6 +737,30 @@ func TestDeleteMessage_Success(t *testing.T) {
 	assert.Empty(t, result.Content)
 }
 
+func TestDeleteMessage_Poll(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	message := utils.Message{
+		ID:       uuid.New(),
+		SenderID: userId,
+		Content:  "Lunch?",
+		Kind:     utils.KIND_POLL,
+		Poll:     &utils.Poll{Question: "Lunch?", Options: []utils.PollOption{{Text: "Pizza", Votes: 1, Voters: []uuid.UUID{userId}}}},
+	}
+
+	mockStorage.On("GetMessage", message.ID).Return(message, nil)
+	mockStorage.On("DeleteMessage", message.ID).Return(nil)
+
+	result, err := service.DeleteMessage(userId, message.ID)
+
+	assert.NoError(t, err)
+	assert.True(t, result.Deleted)
+	assert.Nil(t, result.Poll)
+	mockStorage.AssertExpectations(t)
+}
+
 func TestDeleteMessage_NotFound(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockAuth := new(MockAuthService)
@@ -336,3 +776,1840 @@ func TestDeleteMessage_NotFound(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, http.StatusNotFound, err.(*utils.ServiceError).StatusCode)
 }
+
+func TestReactToMessage_Success(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	messageId := uuid.New()
+	message := utils.Message{ID: messageId, ChatID: chatId, SenderID: uuid.New()}
+
+	mockStorage.On("GetMessage", messageId).Return(message, nil)
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("AddReaction", messageId, mock.MatchedBy(func(r utils.Reaction) bool {
+		return r.Emoji == "👍" && r.UserID == userId
+	})).Return(nil)
+
+	_, err := service.ReactToMessage(userId, messageId, " 👍 ")
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestReactToMessage_NotMember(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	messageId := uuid.New()
+
+	mockStorage.On("GetMessage", messageId).Return(utils.Message{ID: messageId, ChatID: chatId}, nil)
+	mockStorage.On("MemberOfChat", userId, chatId).Return(mongo.ErrNoDocuments)
+
+	_, err := service.ReactToMessage(userId, messageId, "👍")
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything)
+}
+
+func TestReactToMessage_InvalidEmoji(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	_, err := service.ReactToMessage(uuid.New(), uuid.New(), "  ")
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+}
+
+func TestSendMessage_ReplyStartsThread(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	rootId := uuid.New()
+	replyId := uuid.New()
+
+	// the reply is part of the thread started by the root message
+	mockStorage.On("GetMessage", replyId).Return(utils.Message{ID: replyId, ChatID: chatId, ThreadID: &rootId}, nil)
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return *m.ThreadID == rootId && *m.ReplyTo == replyId
+	})).Return(nil)
+	mockStorage.On("AddThreadReply", rootId, mock.AnythingOfType("time.Time")).Return(nil)
+	mockStorage.On("UpdateChatActivity", chatId).Return(nil)
+
+	message, err := service.SendMessage(userId, chatId, "reply", nil, &replyId)
+
+	assert.NoError(t, err)
+	assert.Equal(t, rootId, *message.ThreadID)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestSendMessage_ReplyFromOtherChat(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	replyId := uuid.New()
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("GetMessage", replyId).Return(utils.Message{ID: replyId, ChatID: uuid.New()}, nil)
+
+	_, err := service.SendMessage(userId, chatId, "reply", nil, &replyId)
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "SaveMessage", mock.Anything)
+}
+
+func TestSendMessage_ReplyByNonMember(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	replyId := uuid.New()
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(mongo.ErrNoDocuments)
+
+	_, err := service.SendMessage(userId, chatId, "reply", nil, &replyId)
+
+	// non-members can not find out if the message exists
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "GetMessage", mock.Anything)
+}
+
+func TestGetThread(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	root := utils.Message{ID: uuid.New(), ChatID: chatId, ReplyCount: 1}
+	reply := utils.Message{ID: uuid.New(), ChatID: chatId, ThreadID: &root.ID, ReplyTo: &root.ID}
+
+	mockStorage.On("GetMessage", reply.ID).Return(reply, nil)
+	mockStorage.On("GetMessage", root.ID).Return(root, nil)
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("GetThread", root.ID).Return([]utils.Message{reply}, nil)
+
+	thread, err := service.GetThread(userId, reply.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []utils.Message{root, reply}, thread)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestReadMessage_MarksReadForUser(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	messageId := uuid.New()
+	otherReader := uuid.New()
+
+	// another member has already read the message, that must not count for this user
+	message := utils.Message{
+		ID:       messageId,
+		ChatID:   chatId,
+		SenderID: uuid.New(),
+		Read:     true,
+		ReadBy:   map[string]time.Time{otherReader.String(): time.Now()},
+	}
+
+	mockStorage.On("GetMessage", messageId).Return(message, nil)
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("MarkMessageRead", messageId, userId, mock.AnythingOfType("time.Time")).Return(nil)
+
+	_, err := service.ReadMessage(userId, messageId)
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestGetReceipts(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	sender := uuid.New()
+	reader := uuid.New()
+	unread := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Members: []uuid.UUID{sender, reader, unread}}
+	readAt := time.Now()
+	message := utils.Message{
+		ID:       uuid.New(),
+		ChatID:   chat.ID,
+		SenderID: sender,
+		Read:     true,
+		ReadBy:   map[string]time.Time{reader.String(): readAt},
+	}
+
+	mockStorage.On("GetMessage", message.ID).Return(message, nil)
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+
+	receipts, err := service.GetReceipts(sender, message.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []utils.Receipt{
+		{UserID: reader, Read: true, ReadAt: &readAt},
+		{UserID: unread, Read: false},
+	}, receipts)
+}
+
+func TestGetReceipts_LegacyReadFlag(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	sender := uuid.New()
+	receiver := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Members: []uuid.UUID{sender, receiver}}
+	message := utils.Message{ID: uuid.New(), ChatID: chat.ID, SenderID: sender, Read: true}
+
+	mockStorage.On("GetMessage", message.ID).Return(message, nil)
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+
+	receipts, err := service.GetReceipts(sender, message.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []utils.Receipt{{UserID: receiver, Read: true}}, receipts)
+}
+
+func TestMarkChatRead_UpToMessage(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	lastSeen := utils.Message{ID: uuid.New(), ChatID: chatId, Timestamp: time.Now().Add(-time.Minute)}
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("GetMessage", lastSeen.ID).Return(lastSeen, nil)
+	mockStorage.On("MarkChatRead", chatId, userId, lastSeen.Timestamp, mock.AnythingOfType("time.Time")).Return(int64(3), nil)
+	mockStorage.On("SaveEvent", mock.MatchedBy(func(e utils.Event) bool {
+		return e.Type == utils.EVENT_CHAT_READ && e.ChatID == chatId && e.Count == 3
+	})).Return(nil)
+
+	event, err := service.MarkChatRead(userId, chatId, &lastSeen.ID, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, lastSeen.Timestamp, *event.ReadUpTo)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestMarkChatRead_NothingChanged(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("MarkChatRead", chatId, userId, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+	_, err := service.MarkChatRead(userId, chatId, nil, nil)
+
+	assert.NoError(t, err)
+	mockStorage.AssertNotCalled(t, "SaveEvent", mock.Anything)
+}
+
+func TestSearchMessages_OnlyMemberChats(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatIds := []uuid.UUID{uuid.New(), uuid.New()}
+	found := []utils.SearchResult{{Message: utils.Message{ID: uuid.New(), Content: "Let's deploy on Friday"}, Score: 1.1}}
+
+	mockStorage.On("GetChatIDs", userId).Return(chatIds, nil)
+	mockStorage.On("SearchMessages", mock.MatchedBy(func(q utils.SearchQuery) bool {
+		return q.Query == "deploy" && assert.ObjectsAreEqual(chatIds, q.ChatIDs) && q.Limit == DEFAULT_SEARCH_LIMIT
+	})).Return(found, int64(1), nil)
+
+	results, err := service.SearchMessages(userId, utils.SearchQuery{Query: " deploy "})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), results.Total)
+	assert.Equal(t, "Let's **deploy** on Friday", results.Results[0].Snippet)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestSearchMessages_NotMemberOfChat(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(mongo.ErrNoDocuments)
+
+	_, err := service.SearchMessages(userId, utils.SearchQuery{Query: "deploy", ChatIDs: []uuid.UUID{chatId}})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "SearchMessages", mock.Anything)
+}
+
+func TestHighlight(t *testing.T) {
+	content := strings.Repeat("a", 100) + " Deploy the release " + strings.Repeat("b", 100)
+
+	snippet := highlight(content, searchTerms("deploy -hotfix"))
+
+	assert.True(t, strings.HasPrefix(snippet, "…"))
+	assert.True(t, strings.HasSuffix(snippet, "…"))
+	assert.Contains(t, snippet, " **Deploy** the release ")
+}
+
+func TestGetMessagesPage_Before(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	now := time.Now()
+	anchor := utils.Message{ID: uuid.New(), ChatID: chatId, Timestamp: now}
+	older := []utils.Message{
+		{ID: uuid.New(), ChatID: chatId, Timestamp: now.Add(-3 * time.Minute)},
+		{ID: uuid.New(), ChatID: chatId, Timestamp: now.Add(-2 * time.Minute)},
+		{ID: uuid.New(), ChatID: chatId, Timestamp: now.Add(-1 * time.Minute)},
+	}
+	cursor := utils.MessageCursor{Timestamp: anchor.Timestamp, ID: anchor.ID}
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("GetMessage", anchor.ID).Return(anchor, nil)
+	// one more message than requested is loaded to detect further pages
+	mockStorage.On("GetChatMessagesFrom", chatId, cursor, true, false, 3).Return(older, nil)
+
+	page, err := service.GetMessagesPage(userId, chatId, utils.MessagePageQuery{Before: anchor.ID.String(), Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, older[1:], page.Messages)
+	assert.Equal(t, encodeCursor(older[1]), page.PrevCursor)
+	assert.Equal(t, encodeCursor(older[2]), page.NextCursor)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestGetMessagesPage_CursorRoundTrip(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	message := utils.Message{ID: uuid.New(), Timestamp: time.Now()}
+
+	cursor, err := service.parseCursor(uuid.New(), encodeCursor(message))
+
+	assert.NoError(t, err)
+	assert.Equal(t, message.ID, cursor.ID)
+	assert.True(t, message.Timestamp.Equal(cursor.Timestamp))
+
+	_, err = service.parseCursor(uuid.New(), "not-a-cursor")
+	assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+}
+
+func TestUpdateChat_MemberForbidden(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	owner := uuid.New()
+	member := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{owner, member}, CreatorID: owner}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+
+	_, err := service.UpdateChat(member, chat.ID, "Renamed", []uuid.UUID{member, uuid.New()})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "CreateOrUpdateChat", mock.Anything)
+}
+
+func TestUpdateChat_AdminCannotRemoveOwner(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	owner := uuid.New()
+	admin := uuid.New()
+	member := uuid.New()
+	chat := &utils.Chat{
+		ID:        uuid.New(),
+		Name:      "Team",
+		Members:   []uuid.UUID{owner, admin, member},
+		CreatorID: owner,
+		Roles:     map[string]utils.ChatRole{owner.String(): utils.ROLE_OWNER, admin.String(): utils.ROLE_ADMIN},
+	}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockAuth.On("Exists", []uuid.UUID{admin, member}).Return(true, nil)
+
+	_, err := service.UpdateChat(admin, chat.ID, "Team", []uuid.UUID{admin, member})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "CreateOrUpdateChat", mock.Anything)
+}
+
+func TestUpdateChat_KeepsCreatorAndRoles(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	owner := uuid.New()
+	admin := uuid.New()
+	removed := uuid.New()
+	added := uuid.New()
+	createdAt := time.Now().Add(-time.Hour)
+	chat := &utils.Chat{
+		ID:        uuid.New(),
+		Name:      "Team",
+		Members:   []uuid.UUID{owner, admin, removed},
+		CreatorID: owner,
+		CreatedAt: createdAt,
+		Roles:     map[string]utils.ChatRole{owner.String(): utils.ROLE_OWNER, admin.String(): utils.ROLE_ADMIN},
+	}
+	members := []uuid.UUID{owner, admin, added}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockAuth.On("Exists", members).Return(true, nil)
+	mockStorage.On("CreateOrUpdateChat", mock.AnythingOfType("utils.Chat")).Return(nil)
+
+	updated, err := service.UpdateChat(admin, chat.ID, "Renamed", members)
+
+	assert.NoError(t, err)
+	assert.Equal(t, owner, updated.CreatorID)
+	assert.Equal(t, createdAt, updated.CreatedAt)
+	assert.Equal(t, utils.ROLE_ADMIN, updated.RoleOf(admin))
+	assert.Equal(t, utils.ROLE_MEMBER, updated.RoleOf(added))
+	assert.Equal(t, utils.ChatRole(""), updated.RoleOf(removed))
+}
+
+func TestDeleteChat_OnlyOwner(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	owner := uuid.New()
+	member := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{owner, member}, CreatorID: owner}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("DeleteChat", chat.ID).Return(nil)
+
+	err := service.DeleteChat(member, chat.ID)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+
+	err = service.DeleteChat(owner, chat.ID)
+	assert.NoError(t, err)
+	mockStorage.AssertNumberOfCalls(t, "DeleteChat", 1)
+}
+
+func TestDeleteChat_AIChat(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	// the AI chat is created without a creator or roles
+	userId := uuid.New()
+	chat := &utils.Chat{ID: userId, Name: "AI", Members: []uuid.UUID{userId}}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("DeleteChat", chat.ID).Return(nil)
+
+	err := service.DeleteChat(userId, chat.ID)
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestPromoteMember(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	owner := uuid.New()
+	member := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{owner, member}, CreatorID: owner}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("SetChatRole", chat.ID, member, utils.ROLE_ADMIN).Return(nil)
+
+	_, err := service.PromoteMember(member, chat.ID, member)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+
+	updated, err := service.PromoteMember(owner, chat.ID, member)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.ROLE_ADMIN, updated.RoleOf(member))
+	mockStorage.AssertExpectations(t)
+}
+
+func TestAddMembers(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	owner := uuid.New()
+	member := uuid.New()
+	newMember := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{owner, member}, CreatorID: owner}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockAuth.On("Exists", newMember).Return(true, nil)
+	mockStorage.On("AddChatMembers", chat.ID, []uuid.UUID{newMember}).Return(nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return m.SenderID.String() == utils.AIChat && strings.Contains(m.Content, newMember.String())
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", chat.ID).Return(nil)
+
+	// existing members are ignored
+	updated, err := service.AddMembers(owner, chat.ID, []uuid.UUID{member, newMember, newMember})
+
+	assert.NoError(t, err)
+	assert.Contains(t, updated.Members, newMember)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRemoveMember_AdminCannotRemoveAdmin(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	owner := uuid.New()
+	admin := uuid.New()
+	otherAdmin := uuid.New()
+	chat := &utils.Chat{
+		ID:        uuid.New(),
+		Name:      "Team",
+		Members:   []uuid.UUID{owner, admin, otherAdmin},
+		CreatorID: owner,
+		Roles:     map[string]utils.ChatRole{admin.String(): utils.ROLE_ADMIN, otherAdmin.String(): utils.ROLE_ADMIN},
+	}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+
+	_, err := service.RemoveMember(admin, chat.ID, otherAdmin)
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "RemoveChatMember", mock.Anything, mock.Anything)
+}
+
+func TestLeaveChat_OwnerPassesOwnership(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	owner := uuid.New()
+	member := uuid.New()
+	admin := uuid.New()
+	chat := &utils.Chat{
+		ID:        uuid.New(),
+		Name:      "Team",
+		Members:   []uuid.UUID{owner, member, admin},
+		CreatorID: owner,
+		Roles:     map[string]utils.ChatRole{admin.String(): utils.ROLE_ADMIN},
+	}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("SetChatRole", chat.ID, admin, utils.ROLE_OWNER).Return(nil)
+	mockStorage.On("RemoveChatMember", chat.ID, owner).Return(nil)
+	mockStorage.On("SaveMessage", mock.AnythingOfType("utils.Message")).Return(nil)
+	mockStorage.On("UpdateChatActivity", chat.ID).Return(nil)
+
+	updated, err := service.LeaveChat(owner, chat.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, utils.ROLE_OWNER, updated.RoleOf(admin))
+	assert.NotContains(t, updated.Members, owner)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestPinMessage(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil, WithMaxPins(2))
+
+	userId := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{userId}}
+	message := utils.Message{ID: uuid.New(), ChatID: chat.ID, Content: "Important"}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("GetMessage", message.ID).Return(message, nil)
+	mockStorage.On("AddPin", chat.ID, mock.AnythingOfType("utils.Pin"), 2).Return(true, nil)
+	mockStorage.On("SaveMessage", mock.AnythingOfType("utils.Message")).Return(nil)
+	mockStorage.On("UpdateChatActivity", chat.ID).Return(nil)
+
+	pin, err := service.PinMessage(userId, chat.ID, message.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, message.ID, pin.MessageID)
+	assert.Equal(t, userId, pin.PinnedBy)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestPinMessage_LimitReached(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil, WithMaxPins(1))
+
+	userId := uuid.New()
+	chat := &utils.Chat{
+		ID:      uuid.New(),
+		Name:    "Team",
+		Members: []uuid.UUID{userId},
+		Pins:    []utils.Pin{{MessageID: uuid.New(), PinnedBy: userId}},
+	}
+	message := utils.Message{ID: uuid.New(), ChatID: chat.ID}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("GetMessage", message.ID).Return(message, nil)
+
+	_, err := service.PinMessage(userId, chat.ID, message.ID)
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "AddPin", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUnpinMessage_OnlyPinnerOrAdmin(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	owner := uuid.New()
+	pinner := uuid.New()
+	member := uuid.New()
+	messageId := uuid.New()
+	chat := &utils.Chat{
+		ID:        uuid.New(),
+		Name:      "Team",
+		Members:   []uuid.UUID{owner, pinner, member},
+		CreatorID: owner,
+		Pins:      []utils.Pin{{MessageID: messageId, PinnedBy: pinner}},
+	}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("RemovePin", chat.ID, messageId).Return(true, nil)
+
+	err := service.UnpinMessage(member, chat.ID, messageId)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+
+	assert.NoError(t, service.UnpinMessage(owner, chat.ID, messageId))
+	mockStorage.AssertNumberOfCalls(t, "RemovePin", 1)
+}
+
+func TestScheduleMessage_InThePast(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	_, err := service.ScheduleMessage(uuid.New(), uuid.New(), "Good morning", nil, nil, time.Now().Add(-time.Minute))
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "SaveScheduledMessage", mock.Anything)
+}
+
+func TestScheduleMessage(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	sendAt := time.Now().Add(time.Hour)
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("SaveScheduledMessage", mock.AnythingOfType("utils.ScheduledMessage")).Return(nil)
+
+	scheduled, err := service.ScheduleMessage(userId, chatId, "Stand-up in 5 minutes", nil, nil, sendAt)
+
+	assert.NoError(t, err)
+	assert.Equal(t, utils.SCHEDULED_PENDING, scheduled.Status)
+	assert.Equal(t, sendAt, scheduled.SendAt)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDispatchDueMessages_SendsWithScheduledId(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	scheduled := &utils.ScheduledMessage{
+		ID:       uuid.New(),
+		ChatID:   uuid.New(),
+		SenderID: uuid.New(),
+		Content:  "Good morning",
+		Status:   utils.SCHEDULED_SENDING,
+	}
+
+	mockStorage.On("ClaimScheduledMessage", mock.Anything, SCHEDULE_LEASE).Return(scheduled, nil).Once()
+	mockStorage.On("ClaimScheduledMessage", mock.Anything, SCHEDULE_LEASE).Return(nil, nil).Once()
+	mockStorage.On("GetMessage", scheduled.ID).Return(utils.Message{}, mongo.ErrNoDocuments)
+	mockStorage.On("MemberOfChat", scheduled.SenderID, scheduled.ChatID).Return(nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool { return m.ID == scheduled.ID })).Return(nil)
+	mockStorage.On("UpdateChatActivity", scheduled.ChatID).Return(nil)
+	mockStorage.On("FinishScheduledMessage", scheduled.ID, "").Return(nil)
+
+	service.dispatchDueMessages()
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestDispatchDueMessages_MarksFailed(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	scheduled := &utils.ScheduledMessage{
+		ID:       uuid.New(),
+		ChatID:   uuid.New(),
+		SenderID: uuid.New(),
+		Content:  "Good morning",
+	}
+
+	mockStorage.On("ClaimScheduledMessage", mock.Anything, SCHEDULE_LEASE).Return(scheduled, nil).Once()
+	mockStorage.On("ClaimScheduledMessage", mock.Anything, SCHEDULE_LEASE).Return(nil, nil).Once()
+	mockStorage.On("GetMessage", scheduled.ID).Return(utils.Message{}, mongo.ErrNoDocuments)
+	mockStorage.On("MemberOfChat", scheduled.SenderID, scheduled.ChatID).Return(mongo.ErrNoDocuments)
+	mockStorage.On("FinishScheduledMessage", scheduled.ID, "User is not a member of the chat").Return(nil)
+
+	service.dispatchDueMessages()
+
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "SaveMessage", mock.Anything)
+}
+
+func TestSetRetention_OnlyAdmins(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	owner := uuid.New()
+	member := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{owner, member}, CreatorID: owner}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+
+	_, err := service.SetRetention(member, chat.ID, utils.Retention{Days: 7})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "SetChatRetention", mock.Anything, mock.Anything)
+}
+
+func TestSetRetention_AnnouncesChange(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	owner := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{owner, uuid.New()}, CreatorID: owner}
+	retention := utils.Retention{Days: 7}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("SetChatRetention", chat.ID, &retention).Return(nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Contains(m.Content, "7 days")
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", chat.ID).Return(nil)
+
+	updated, err := service.SetRetention(owner, chat.ID, retention)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, updated.Retention.MaxAge())
+	mockStorage.AssertExpectations(t)
+}
+
+func TestExpireMessages_UsesShortestAge(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	now := time.Now()
+	chat := utils.Chat{ID: uuid.New(), Retention: &utils.Retention{Days: 1, MessageTTL: 3600}}
+
+	mockStorage.On("GetChatsWithRetention").Return([]utils.Chat{chat}, nil)
+	mockStorage.On("ExpireMessages", chat.ID, now.Add(-time.Hour)).Return(int64(2), nil)
+
+	service.expireMessages(now)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestCreateInvite_TokenRoundTrip(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil, WithInviteSecret("secret"))
+
+	userId := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{userId, uuid.New()}}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("SaveInvite", mock.AnythingOfType("utils.Invite")).Return(nil)
+
+	invite, err := service.CreateInvite(userId, chat.ID, nil, 5)
+	assert.NoError(t, err)
+
+	inviteId, ok := service.parseInviteToken(invite.Token)
+	assert.True(t, ok)
+	assert.Equal(t, invite.ID, inviteId)
+
+	// tokens signed with another secret are rejected
+	other := New(mockStorage, nil, nil, WithInviteSecret("other"))
+	_, ok = other.parseInviteToken(invite.Token)
+	assert.False(t, ok)
+}
+
+func TestJoinChat(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{uuid.New(), uuid.New()}}
+	invite := &utils.Invite{ID: uuid.New(), ChatID: chat.ID}
+
+	mockStorage.On("GetInvite", invite.ID).Return(invite, nil)
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("UseInvite", invite.ID, mock.AnythingOfType("time.Time")).Return(true, nil)
+	mockStorage.On("AddChatMembers", chat.ID, []uuid.UUID{userId}).Return(nil)
+	mockStorage.On("SaveMessage", mock.AnythingOfType("utils.Message")).Return(nil)
+	mockStorage.On("UpdateChatActivity", chat.ID).Return(nil)
+
+	joined, err := service.JoinChat(userId, service.inviteToken(invite.ID))
+
+	assert.NoError(t, err)
+	assert.Contains(t, joined.Members, userId)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestJoinChat_UsedUp(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{uuid.New(), uuid.New()}}
+	invite := &utils.Invite{ID: uuid.New(), ChatID: chat.ID, MaxUses: 1, Uses: 1}
+
+	mockStorage.On("GetInvite", invite.ID).Return(invite, nil)
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("UseInvite", invite.ID, mock.AnythingOfType("time.Time")).Return(false, nil)
+
+	_, err := service.JoinChat(userId, service.inviteToken(invite.ID))
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusGone, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "AddChatMembers", mock.Anything, mock.Anything)
+}
+
+func TestGetChats_MergesSettings(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	archivedChat := utils.Chat{ID: uuid.New(), Name: "Old", Members: []uuid.UUID{userId}}
+	mutedChat := utils.Chat{ID: uuid.New(), Name: "Noisy", Members: []uuid.UUID{userId}}
+	favouriteChat := utils.Chat{ID: uuid.New(), Name: "Team", Members: []uuid.UUID{userId}}
+	aiChat := utils.Chat{ID: userId, Name: "AI", Members: []uuid.UUID{userId}}
+
+	mutedUntil := time.Now().Add(time.Hour)
+	mockStorage.On("GetChats", userId, mock.AnythingOfType("utils.ChatListOptions")).Return([]utils.Chat{archivedChat, mutedChat, favouriteChat, aiChat}, nil)
+	mockStorage.On("GetChatSettings", userId).Return([]utils.ChatSettings{
+		{ChatID: archivedChat.ID, UserID: userId, Archived: true},
+		{ChatID: mutedChat.ID, UserID: userId, MutedUntil: &mutedUntil},
+		{ChatID: favouriteChat.ID, UserID: userId, Favourite: true},
+	}, nil)
+
+	chats, err := service.GetChats(userId, utils.ChatListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, chats, 4)
+	assert.Equal(t, favouriteChat.ID, chats[0].ID)
+	assert.True(t, slices.ContainsFunc(chats, func(c utils.Chat) bool { return c.ID == mutedChat.ID && c.Muted }))
+
+	archived := true
+	chats, err = service.GetChats(userId, utils.ChatListOptions{Archived: &archived})
+	assert.NoError(t, err)
+	assert.Len(t, chats, 1)
+	assert.Equal(t, archivedChat.ID, chats[0].ID)
+}
+
+type echoCommand struct {
+	ran chan CommandContext
+}
+
+func (echoCommand) Name() string { return "echo" }
+
+func (echoCommand) Usage() string { return "/echo [text]" }
+
+func (echoCommand) Description() string { return "Repeats the text" }
+
+func (echoCommand) ParseArgs(args string) ([]string, error) { return strings.Fields(args), nil }
+
+func (e echoCommand) Run(ctx CommandContext) error {
+	e.ran <- ctx
+	return nil
+}
+
+func TestRegisterCommand(t *testing.T) {
+	service := New(new(MockStorage), nil, nil, WithCommands(echoCommand{}))
+
+	assert.Error(t, service.RegisterCommand(echoCommand{}))
+	assert.Error(t, service.RegisterCommand(helpCommand{}))
+
+	commands := service.GetCommands()
+	names := []string{}
+	for _, command := range commands {
+		names = append(names, command.Name)
+	}
+	assert.Equal(t, []string{"answer", "echo", "guess", "help", "quiz"}, names)
+}
+
+func TestCommand_RunsRegisteredCommand(t *testing.T) {
+	mockStorage := new(MockStorage)
+	echo := echoCommand{ran: make(chan CommandContext, 1)}
+	service := New(mockStorage, nil, nil, WithCommands(echo))
+
+	userId := uuid.New()
+	chatId := uuid.New()
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("SaveMessage", mock.AnythingOfType("utils.Message")).Return(nil)
+	mockStorage.On("UpdateChatActivity", chatId).Return(nil)
+
+	message, err := service.Command(userId, chatId, "hello world", "/Echo")
+	assert.NoError(t, err)
+	assert.Equal(t, "echo", message.Command)
+	assert.Equal(t, utils.KIND_COMMAND, message.Kind)
+	assert.Equal(t, &utils.CommandPayload{Name: "echo", Args: []string{"hello", "world"}}, message.Payload.Command)
+
+	select {
+	case ctx := <-echo.ran:
+		assert.Equal(t, []string{"hello", "world"}, ctx.Args)
+		assert.Equal(t, chatId, ctx.ChatID)
+		assert.Equal(t, "echo", ctx.Name)
+	case <-time.After(time.Second):
+		t.Fatal("command did not run")
+	}
+}
+
+func TestCommand_InvalidArgs(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	_, err := service.Command(uuid.New(), uuid.New(), "", "guess")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+
+	_, err = service.Command(uuid.New(), uuid.New(), "", "unknown")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "SaveMessage", mock.Anything)
+}
+
+func TestGuessWord_LastWordFinishesGame(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	game := &utils.Game{
+		ID:       uuid.New(),
+		ChatID:   uuid.New(),
+		Words:    []string{"Apple", "Pear"},
+		Guesses:  []utils.GameGuess{{Word: "Pear", UserID: uuid.New()}},
+		Status:   utils.GAME_RUNNING,
+		Deadline: time.Now().Add(time.Minute),
+	}
+	finished := *game
+	finished.Guesses = append(finished.Guesses, utils.GameGuess{Word: "Apple", UserID: userId})
+
+	mockStorage.On("AddGameGuess", game.ID, mock.MatchedBy(func(g utils.GameGuess) bool {
+		return g.Word == "Apple" && g.UserID == userId
+	})).Return(&finished, nil)
+	mockStorage.On("FinishGame", game.ID, utils.GAME_WON, mock.AnythingOfType("time.Time")).Return(true, nil)
+	mockStorage.On("RecordGameScores", game.ChatID, mock.MatchedBy(func(scores []utils.GameScore) bool {
+		// both players guessed one word, so both of them win
+		return len(scores) == 2 && scores[0].Wins == 1 && scores[1].Wins == 1
+	})).Return(nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Contains(m.Content, "all words have been guessed")
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", game.ChatID).Return(nil)
+
+	ctx := CommandContext{UserID: userId, ChatID: game.ChatID, chat: &service}
+	err := service.guessWord(ctx, game, "apple")
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestGuessWord_AlreadyGuessed(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	game := &utils.Game{
+		ID:       uuid.New(),
+		ChatID:   uuid.New(),
+		Words:    []string{"Apple", "Pear"},
+		Status:   utils.GAME_RUNNING,
+		Deadline: time.Now().Add(time.Minute),
+	}
+
+	mockStorage.On("AddGameGuess", game.ID, mock.AnythingOfType("utils.GameGuess")).Return(nil, nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Contains(m.Content, "already been guessed")
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", game.ChatID).Return(nil)
+
+	ctx := CommandContext{UserID: uuid.New(), ChatID: game.ChatID, chat: &service}
+	err := service.guessWord(ctx, game, "Apple")
+
+	assert.NoError(t, err)
+	mockStorage.AssertNotCalled(t, "FinishGame", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestStartGuessingGame_DeduplicatesWords(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAi := new(MockAiService)
+	service := New(mockStorage, nil, mockAi)
+
+	chatId := uuid.New()
+	options := utils.GameOptions{Duration: 60, Words: 3, Difficulty: DEFAULT_DIFFICULTY}
+
+	mockStorage.On("StartGame", mock.AnythingOfType("utils.Game")).Return(true, nil)
+	mockAi.On("GuessWords", "fruit", 3, DEFAULT_DIFFICULTY).Return([]string{"Apple", "apple ", "Pear"}, nil)
+	mockAi.On("GuessWords", "fruit", 1, DEFAULT_DIFFICULTY).Return([]string{"PEAR", "Plum"}, nil)
+	mockStorage.On("SetGameWords", mock.AnythingOfType("uuid.UUID"), []string{"Apple", "Pear", "Plum"}, mock.AnythingOfType("time.Time")).Return(true, nil)
+	mockStorage.On("SaveMessage", mock.AnythingOfType("utils.Message")).Return(nil)
+	mockStorage.On("UpdateChatActivity", chatId).Return(nil)
+
+	ctx := CommandContext{UserID: uuid.New(), ChatID: chatId, chat: &service}
+	err := service.startGuessingGame(ctx, "fruit", options)
+
+	assert.NoError(t, err)
+	mockAi.AssertExpectations(t)
+	mockStorage.AssertCalled(t, "SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Contains(m.Content, "There are **3** words")
+	}))
+}
+
+func TestStartGuessingGame_ExpiredWhileStarting(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAi := new(MockAiService)
+	service := New(mockStorage, nil, mockAi)
+
+	chatId := uuid.New()
+	options := utils.GameOptions{Duration: 60, Words: 3, Difficulty: DEFAULT_DIFFICULTY}
+
+	mockStorage.On("StartGame", mock.AnythingOfType("utils.Game")).Return(true, nil)
+	mockAi.On("GuessWords", "fruit", 3, DEFAULT_DIFFICULTY).Return([]string{"Apple", "Pear", "Plum"}, nil)
+	mockStorage.On("SetGameWords", mock.AnythingOfType("uuid.UUID"), []string{"Apple", "Pear", "Plum"}, mock.AnythingOfType("time.Time")).Return(false, nil)
+	mockStorage.On("SaveMessage", mock.AnythingOfType("utils.Message")).Return(nil)
+	mockStorage.On("UpdateChatActivity", chatId).Return(nil)
+
+	ctx := CommandContext{UserID: uuid.New(), ChatID: chatId, chat: &service}
+	err := service.startGuessingGame(ctx, "fruit", options)
+
+	assert.NoError(t, err)
+	mockStorage.AssertNotCalled(t, "SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Contains(m.Content, "has started")
+	}))
+}
+
+func TestExpireGames_AnnouncesClaimedGames(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	now := time.Now()
+	game := &utils.Game{ID: uuid.New(), ChatID: uuid.New(), Words: []string{"Apple"}, Status: utils.GAME_EXPIRED}
+
+	mockStorage.On("ClaimExpiredGame", now).Return(game, nil).Once()
+	mockStorage.On("ClaimExpiredGame", now).Return(nil, nil).Once()
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return m.ChatID == game.ChatID && strings.Contains(m.Content, "Game Over")
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", game.ChatID).Return(nil)
+
+	service.expireGames(now)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestGuessCommand_OptionsOnlyWhenStarting(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	chatId := uuid.New()
+	game := &utils.Game{
+		ID:       uuid.New(),
+		ChatID:   chatId,
+		Words:    []string{"E=mc2"},
+		Status:   utils.GAME_RUNNING,
+		Deadline: time.Now().Add(time.Minute),
+	}
+
+	// a guess that looks like an option is passed to the running game
+	args, err := guessCommand{}.ParseArgs("words=100")
+	assert.NoError(t, err)
+
+	mockStorage.On("GetActiveGame", chatId).Return(game, nil).Once()
+	mockStorage.On("AddGameGuess", game.ID, mock.MatchedBy(func(g utils.GameGuess) bool {
+		return g.Word == "words=100"
+	})).Return(nil, nil)
+	mockStorage.On("SaveMessage", mock.AnythingOfType("utils.Message")).Return(nil)
+	mockStorage.On("UpdateChatActivity", chatId).Return(nil)
+
+	ctx := CommandContext{UserID: uuid.New(), ChatID: chatId, Args: args, chat: &service}
+	err = guessCommand{}.Run(ctx)
+	assert.NoError(t, err)
+
+	// without a game the options are checked and reported to the user
+	mockStorage.On("GetActiveGame", chatId).Return(nil, nil).Once()
+
+	err = guessCommand{}.Run(ctx)
+	assert.NoError(t, err)
+	mockStorage.AssertCalled(t, "SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Contains(m.Content, "words has to be between")
+	}))
+	mockStorage.AssertNotCalled(t, "StartGame", mock.Anything)
+}
+
+func TestParseGameOptions(t *testing.T) {
+	options, topic, err := parseGameOptions([]string{"space", "travel", "words=5", "duration=90s", "difficulty=Hard", "hints=on"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "space travel", topic)
+	assert.Equal(t, utils.GameOptions{Duration: 90, Words: 5, Difficulty: "hard", Hints: true}, options)
+
+	options, _, err = parseGameOptions([]string{"animals"})
+	assert.NoError(t, err)
+	assert.Equal(t, DEFAULT_GAME_WORDS, options.Words)
+	assert.False(t, options.Hints)
+
+	for _, arg := range []string{"words=100", "duration=1h", "difficulty=extreme", "hints=maybe", "rounds=3"} {
+		_, _, err = parseGameOptions([]string{"animals", arg})
+		assert.Error(t, err, arg)
+	}
+}
+
+func TestRevealHint(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	game := &utils.Game{
+		ID:      uuid.New(),
+		ChatID:  uuid.New(),
+		Words:   []string{"Pear", "Apple"},
+		Guesses: []utils.GameGuess{{Word: "Pear", UserID: uuid.New()}},
+		Status:  utils.GAME_RUNNING,
+		Options: utils.GameOptions{Hints: true},
+		Hints:   []int{0, 1},
+	}
+	updated := *game
+	updated.Hints = []int{0, 2}
+
+	mockStorage.On("AddGameHint", game.ID, 1).Return(&updated, nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Contains(m.Content, "A p _ _ _")
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", game.ChatID).Return(nil)
+
+	ctx := CommandContext{UserID: uuid.New(), ChatID: game.ChatID, chat: &service}
+	err := service.revealHint(ctx, game)
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestGetGameLeaderboard(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	other := uuid.New()
+	scores := []utils.GameScore{
+		{ChatID: chatId, UserID: userId, Words: 12, Games: 3, Wins: 2},
+		{ChatID: chatId, UserID: other, Words: 4, Games: 3, Wins: 1},
+	}
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("GetGameLeaderboard", chatId, LEADERBOARD_SIZE).Return(scores, nil)
+	mockAuth.On("GetUsers", []uuid.UUID{userId, other}).Return(map[uuid.UUID]utils.User{
+		userId: {ID: userId, FirstName: "Ada", LastName: "Lovelace"},
+	}, nil)
+
+	result, err := service.GetGameLeaderboard(userId, chatId)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Ada Lovelace", result[0].DisplayName)
+	assert.Equal(t, "@"+other.String(), result[1].DisplayName)
+}
+
+func TestAnswerQuestion_ScoresFastCorrectAnswers(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	quiz := &utils.Quiz{
+		ID:                uuid.New(),
+		ChatID:            uuid.New(),
+		Questions:         []utils.QuizQuestion{{Question: "2 + 2?", Options: []string{"3", "4", "5"}, Answer: 1}},
+		Status:            utils.QUIZ_RUNNING,
+		QuestionStartedAt: time.Now(),
+		Deadline:          time.Now().Add(QUIZ_QUESTION_DURATION),
+	}
+
+	mockStorage.On("AddQuizAnswer", quiz.ID, mock.MatchedBy(func(a utils.QuizAnswer) bool {
+		return a.UserID == userId && a.Option == 1 && a.Correct && a.Points > QUIZ_POINTS*9/10
+	})).Return(true, nil)
+
+	ctx := CommandContext{UserID: userId, ChatID: quiz.ChatID, chat: &service}
+	err := service.answerQuestion(ctx, quiz, 1)
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "SaveMessage", mock.Anything)
+}
+
+func TestAnswerQuestion_AlreadyAnswered(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	quiz := &utils.Quiz{
+		ID:                uuid.New(),
+		ChatID:            uuid.New(),
+		Questions:         []utils.QuizQuestion{{Question: "2 + 2?", Options: []string{"3", "4"}, Answer: 1}},
+		Status:            utils.QUIZ_RUNNING,
+		QuestionStartedAt: time.Now(),
+	}
+
+	mockStorage.On("AddQuizAnswer", quiz.ID, mock.MatchedBy(func(a utils.QuizAnswer) bool {
+		return !a.Correct && a.Points == 0
+	})).Return(false, nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Contains(m.Content, "already answered")
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", quiz.ChatID).Return(nil)
+
+	ctx := CommandContext{UserID: uuid.New(), ChatID: quiz.ChatID, chat: &service}
+	err := service.answerQuestion(ctx, quiz, 0)
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestAnswerCommand_ParseArgs(t *testing.T) {
+	args, err := answerCommand{}.ParseArgs(" b ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"B"}, args)
+
+	for _, arg := range []string{"", "E", "AB", "1"} {
+		_, err = answerCommand{}.ParseArgs(arg)
+		assert.Error(t, err, arg)
+	}
+}
+
+func TestAdvanceQuizzes_AsksNextQuestion(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	now := time.Now()
+	quiz := &utils.Quiz{
+		ID:     uuid.New(),
+		ChatID: uuid.New(),
+		Questions: []utils.QuizQuestion{
+			{Question: "2 + 2?", Options: []string{"3", "4"}, Answer: 1},
+			{Question: "Capital of France?", Options: []string{"Paris", "Rome"}, Answer: 0},
+		},
+		Status: utils.QUIZ_RUNNING,
+	}
+
+	mockStorage.On("ClaimQuizQuestion", now, now.Add(QUIZ_QUESTION_DURATION)).Return(quiz, nil).Once()
+	mockStorage.On("ClaimQuizQuestion", now, now.Add(QUIZ_QUESTION_DURATION)).Return(nil, nil).Once()
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Contains(m.Content, "B) 4") && strings.Contains(m.Content, "Question 2/2")
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", quiz.ChatID).Return(nil)
+
+	service.advanceQuizzes(now)
+
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "FinishQuiz", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAdvanceQuizzes_PostsRankingAfterLastQuestion(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	now := time.Now()
+	fast, slow := uuid.New(), uuid.New()
+	quiz := &utils.Quiz{
+		ID:        uuid.New(),
+		ChatID:    uuid.New(),
+		Questions: []utils.QuizQuestion{{Question: "2 + 2?", Options: []string{"3", "4"}, Answer: 1}},
+		Answers: []utils.QuizAnswer{
+			{Question: 0, UserID: slow, Option: 1, Correct: true, Points: 600},
+			{Question: 0, UserID: fast, Option: 1, Correct: true, Points: 900},
+		},
+		Status: utils.QUIZ_RUNNING,
+	}
+
+	mockStorage.On("ClaimQuizQuestion", now, now.Add(QUIZ_QUESTION_DURATION)).Return(quiz, nil).Once()
+	mockStorage.On("ClaimQuizQuestion", now, now.Add(QUIZ_QUESTION_DURATION)).Return(nil, nil).Once()
+	mockStorage.On("FinishQuiz", quiz.ID, utils.QUIZ_FINISHED, now).Return(true, nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Index(m.Content, fast.String()) < strings.Index(m.Content, slow.String()) &&
+			strings.Contains(m.Content, "2 of 2 answers were correct")
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", quiz.ChatID).Return(nil)
+
+	service.advanceQuizzes(now)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestStartQuiz_ExpiredWhileStarting(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAi := new(MockAiService)
+	service := New(mockStorage, nil, mockAi)
+
+	chatId := uuid.New()
+	questions := []utils.QuizQuestion{{Question: "2 + 2?", Options: []string{"3", "4"}, Answer: 1}}
+
+	mockStorage.On("StartQuiz", mock.AnythingOfType("utils.Quiz")).Return(true, nil)
+	mockAi.On("GenerateQuiz", "math", QUIZ_QUESTIONS).Return(questions, nil)
+	mockStorage.On("SetQuizQuestions", mock.AnythingOfType("uuid.UUID"), questions, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).Return(false, nil)
+	mockStorage.On("SaveMessage", mock.AnythingOfType("utils.Message")).Return(nil)
+	mockStorage.On("UpdateChatActivity", chatId).Return(nil)
+
+	ctx := CommandContext{UserID: uuid.New(), ChatID: chatId, chat: &service}
+	err := service.startQuiz(ctx, "math")
+
+	assert.NoError(t, err)
+	mockStorage.AssertNotCalled(t, "SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return strings.Contains(m.Content, "has started")
+	}))
+}
+
+func TestAdvanceQuizzes_FailsQuizThatDidNotStart(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	now := time.Now()
+	quiz := &utils.Quiz{ID: uuid.New(), ChatID: uuid.New(), Questions: []utils.QuizQuestion{}, Status: utils.QUIZ_STARTING}
+
+	mockStorage.On("ClaimQuizQuestion", now, now.Add(QUIZ_QUESTION_DURATION)).Return(quiz, nil).Once()
+	mockStorage.On("ClaimQuizQuestion", now, now.Add(QUIZ_QUESTION_DURATION)).Return(nil, nil).Once()
+	mockStorage.On("FinishQuiz", quiz.ID, utils.QUIZ_FAILED, now).Return(true, nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return m.ChatID == quiz.ChatID && strings.Contains(m.Content, "could not be started")
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", quiz.ChatID).Return(nil)
+
+	service.advanceQuizzes(now)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestCreatePoll(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return m.Content == "Lunch?" && m.Poll != nil && len(m.Poll.Options) == 2 && m.Poll.Voters == 0
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", chatId).Return(nil)
+
+	message, err := service.CreatePoll(userId, chatId, utils.Poll{
+		Question: " Lunch? ",
+		Options:  []utils.PollOption{{Text: "Pizza"}, {Text: "Sushi", Votes: 5}},
+		Voters:   5,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, message.Poll.Options[1].Votes)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestCreatePoll_InvalidOptions(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	for _, options := range [][]utils.PollOption{
+		{{Text: "Pizza"}},
+		{{Text: "Pizza"}, {Text: "pizza"}},
+		{{Text: "Pizza"}, {Text: " "}},
+	} {
+		_, err := service.CreatePoll(uuid.New(), uuid.New(), utils.Poll{Question: "Lunch?", Options: options})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+	}
+	mockStorage.AssertNotCalled(t, "SaveMessage", mock.Anything)
+}
+
+func TestVote(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	message := utils.Message{
+		ID:     uuid.New(),
+		ChatID: uuid.New(),
+		Poll:   &utils.Poll{Question: "Lunch?", Options: []utils.PollOption{{Text: "Pizza"}, {Text: "Sushi"}}},
+	}
+
+	mockStorage.On("GetMessage", message.ID).Return(message, nil)
+	mockStorage.On("MemberOfChat", userId, message.ChatID).Return(nil)
+	mockStorage.On("SavePollVote", mock.MatchedBy(func(v utils.PollVote) bool {
+		return v.UserID == userId && slices.Equal(v.Options, []int{1})
+	})).Return(true, nil)
+	mockStorage.On("CountPollVote", mock.AnythingOfType("utils.PollVote"), false).Return(true, nil)
+
+	_, err := service.Vote(userId, message.ID, []int{1})
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestVote_Rejected(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	closedAt := time.Now().Add(-time.Minute)
+	open := utils.Message{
+		ID:     uuid.New(),
+		ChatID: uuid.New(),
+		Poll:   &utils.Poll{Question: "Lunch?", Options: []utils.PollOption{{Text: "Pizza"}, {Text: "Sushi"}}},
+	}
+	closed := utils.Message{
+		ID:     uuid.New(),
+		ChatID: open.ChatID,
+		Poll:   &utils.Poll{Question: "Dinner?", Options: []utils.PollOption{{Text: "Pasta"}, {Text: "Salad"}}, ClosesAt: &closedAt},
+	}
+
+	mockStorage.On("GetMessage", open.ID).Return(open, nil)
+	mockStorage.On("GetMessage", closed.ID).Return(closed, nil)
+	mockStorage.On("MemberOfChat", userId, open.ChatID).Return(nil)
+	mockStorage.On("SavePollVote", mock.AnythingOfType("utils.PollVote")).Return(false, nil)
+
+	tests := []struct {
+		messageId uuid.UUID
+		options   []int
+		status    int
+	}{
+		{closed.ID, []int{0}, http.StatusConflict},
+		{open.ID, []int{0, 1}, http.StatusBadRequest},
+		{open.ID, []int{2}, http.StatusBadRequest},
+		{open.ID, []int{}, http.StatusBadRequest},
+		{open.ID, []int{0}, http.StatusConflict},
+	}
+
+	for _, test := range tests {
+		_, err := service.Vote(userId, test.messageId, test.options)
+		assert.Error(t, err)
+		assert.Equal(t, test.status, err.(*utils.ServiceError).StatusCode, test.options)
+	}
+	mockStorage.AssertNotCalled(t, "CountPollVote", mock.Anything, mock.Anything)
+}
+
+func TestVote_PollClosedWhileVoting(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	message := utils.Message{
+		ID:     uuid.New(),
+		ChatID: uuid.New(),
+		Poll:   &utils.Poll{Question: "Lunch?", Options: []utils.PollOption{{Text: "Pizza"}, {Text: "Sushi"}}, Anonymous: true},
+	}
+
+	mockStorage.On("GetMessage", message.ID).Return(message, nil)
+	mockStorage.On("MemberOfChat", userId, message.ChatID).Return(nil)
+	mockStorage.On("SavePollVote", mock.AnythingOfType("utils.PollVote")).Return(true, nil)
+	mockStorage.On("CountPollVote", mock.AnythingOfType("utils.PollVote"), true).Return(false, nil)
+	mockStorage.On("DeletePollVote", message.ID, userId).Return(nil)
+
+	_, err := service.Vote(userId, message.ID, []int{0})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestClosePoll_OnlyCreatorOrAdmin(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	creator, member := uuid.New(), uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Members: []uuid.UUID{creator, member}}
+	message := utils.Message{
+		ID:       uuid.New(),
+		ChatID:   chat.ID,
+		SenderID: creator,
+		Poll:     &utils.Poll{Question: "Lunch?", Options: []utils.PollOption{{Text: "Pizza"}, {Text: "Sushi"}}},
+	}
+
+	mockStorage.On("GetMessage", message.ID).Return(message, nil)
+	mockStorage.On("MemberOfChat", mock.Anything, chat.ID).Return(nil)
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("ClosePoll", message.ID, mock.AnythingOfType("time.Time")).Return(true, nil)
+
+	_, err := service.ClosePoll(member, message.ID)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+
+	_, err = service.ClosePoll(creator, message.ID)
+	assert.NoError(t, err)
+	mockStorage.AssertNumberOfCalls(t, "ClosePoll", 1)
+}
+
+func TestForwardMessage(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	target := uuid.New()
+	media := []uuid.UUID{uuid.New()}
+	original := utils.Message{
+		ID:        uuid.New(),
+		ChatID:    uuid.New(),
+		SenderID:  uuid.New(),
+		Content:   "look at this",
+		Media:     media,
+		Timestamp: time.Now().Add(-time.Hour),
+	}
+
+	mockStorage.On("GetMessage", original.ID).Return(original, nil)
+	mockStorage.On("MemberOfChat", userId, original.ChatID).Return(nil)
+	mockStorage.On("MemberOfChat", userId, target).Return(nil)
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return m.ChatID == target && m.SenderID == userId && slices.Equal(m.Media, media) &&
+			m.ForwardedFrom != nil && m.ForwardedFrom.SenderID == original.SenderID &&
+			m.ForwardedFrom.ChatID == original.ChatID && m.ForwardedFrom.MessageID == original.ID
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", target).Return(nil)
+
+	forwarded, err := service.ForwardMessage(userId, original.ID, []uuid.UUID{target, target})
+
+	assert.NoError(t, err)
+	assert.Len(t, forwarded, 1)
+	mockStorage.AssertNumberOfCalls(t, "SaveMessage", 1)
+}
+
+func TestForwardMessage_NotMemberOfTarget(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	member, stranger := uuid.New(), uuid.New()
+	original := utils.Message{ID: uuid.New(), ChatID: uuid.New(), Content: "look at this"}
+
+	mockStorage.On("GetMessage", original.ID).Return(original, nil)
+	mockStorage.On("MemberOfChat", userId, original.ChatID).Return(nil)
+	mockStorage.On("MemberOfChat", userId, member).Return(nil)
+	mockStorage.On("MemberOfChat", userId, stranger).Return(mongo.ErrNoDocuments)
+
+	_, err := service.ForwardMessage(userId, original.ID, []uuid.UUID{member, stranger})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "SaveMessage", mock.Anything)
+}
+
+func exportFixture(mockStorage *MockStorage, mockAuth *MockAuthService) (uuid.UUID, uuid.UUID, []utils.Message) {
+	userId := uuid.New()
+	chat := &utils.Chat{ID: uuid.New(), Name: "Project <X>", Members: []uuid.UUID{userId}}
+	first := utils.Message{ID: uuid.New(), ChatID: chat.ID, SenderID: userId, Content: "hello", Media: []uuid.UUID{uuid.New()}, Timestamp: time.Now()}
+	reply := utils.Message{ID: uuid.New(), ChatID: chat.ID, SenderID: userId, ReplyTo: &first.ID, Deleted: true, Timestamp: time.Now()}
+	messages := []utils.Message{first, reply}
+
+	mockStorage.On("MemberOfChat", userId, chat.ID).Return(nil)
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockStorage.On("GetChatMessagesFrom", chat.ID, utils.MessageCursor{}, false, false, EXPORT_PAGE_SIZE).Return(messages, nil)
+	mockAuth.On("GetUsers", []uuid.UUID{userId}).Return(map[uuid.UUID]utils.User{
+		userId: {ID: userId, FirstName: "Ada", LastName: "Lovelace"},
+	}, nil).Once()
+
+	return userId, chat.ID, messages
+}
+
+func TestExportChat_JSON(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+	userId, chatId, messages := exportFixture(mockStorage, mockAuth)
+
+	export, err := service.ExportChat(userId, chatId, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", export.ContentType())
+
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(&out))
+
+	var result struct {
+		Chat     utils.Chat        `json:"chat"`
+		Messages []ExportedMessage `json:"messages"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, chatId, result.Chat.ID)
+	assert.Len(t, result.Messages, 2)
+	assert.Equal(t, "Ada Lovelace", result.Messages[0].SenderName)
+	assert.Equal(t, messages[0].Media, result.Messages[0].Media)
+	assert.True(t, result.Messages[1].Deleted)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestExportChat_HTML(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+	userId, chatId, messages := exportFixture(mockStorage, mockAuth)
+
+	export, err := service.ExportChat(userId, chatId, "HTML")
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(&out))
+
+	assert.Contains(t, out.String(), "<h1>Project &lt;X&gt;</h1>")
+	assert.Contains(t, out.String(), `href="#msg-`+messages[0].ID.String()+`"`)
+	assert.Contains(t, out.String(), "/media/"+messages[0].Media[0].String())
+	assert.Contains(t, out.String(), "This message was deleted.")
+}
+
+func TestExportChat_InvalidFormat(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	_, err := service.ExportChat(uuid.New(), uuid.New(), "pdf")
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "GetChatMessagesFrom", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFindLinks(t *testing.T) {
+	links := findLinks("see https://commz.de/docs, (http://example.com/a?b=c) and https://commz.de/docs again")
+
+	assert.Equal(t, []string{"https://commz.de/docs", "http://example.com/a?b=c"}, links)
+	assert.Empty(t, findLinks("no links here, not even ftp://example.com"))
+	assert.Len(t, findLinks("https://a.de https://b.de https://c.de https://d.de"), MAX_LINK_PREVIEWS)
+}
+
+func TestUnfurlLinks_StoresPreviews(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockUnfurler := new(MockUnfurler)
+	service := New(mockStorage, nil, nil, WithUnfurler(mockUnfurler))
+
+	messageId := uuid.New()
+	content := "look at https://commz.de and https://broken.example"
+	preview := &utils.LinkPreview{URL: "https://commz.de", Title: "Commz"}
+
+	mockUnfurler.On("Unfurl", "https://commz.de").Return(preview, nil)
+	mockUnfurler.On("Unfurl", "https://broken.example").Return(nil, assert.AnError)
+	mockStorage.On("SetMessagePreviews", messageId, content, []utils.LinkPreview{*preview}).Return(true, nil)
+
+	service.unfurlLinks(messageId, content, false)
+
+	mockUnfurler.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestUnfurlLinks_RemovesStalePreviews(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockUnfurler := new(MockUnfurler)
+	service := New(mockStorage, nil, nil, WithUnfurler(mockUnfurler))
+
+	messageId := uuid.New()
+
+	// without links nothing is fetched and nothing is stored
+	service.unfurlLinks(messageId, "no links", false)
+	mockStorage.AssertNotCalled(t, "SetMessagePreviews", mock.Anything, mock.Anything, mock.Anything)
+
+	// an edit that removed the links removes the previews
+	mockStorage.On("SetMessagePreviews", messageId, "no links", []utils.LinkPreview{}).Return(true, nil)
+	service.unfurlLinks(messageId, "no links", true)
+
+	mockUnfurler.AssertNotCalled(t, "Unfurl", mock.Anything)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestSendMessage_Kind(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	media := []uuid.UUID{uuid.New()}
+
+	mockStorage.On("MemberOfChat", userId, chatId).Return(nil)
+	mockStorage.On("SaveMessage", mock.AnythingOfType("utils.Message")).Return(nil)
+	mockStorage.On("UpdateChatActivity", chatId).Return(nil)
+
+	text, err := service.SendMessage(userId, chatId, "hello", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.KIND_TEXT, text.Kind)
+	assert.Nil(t, text.Payload)
+
+	captioned, err := service.SendMessage(userId, chatId, "a photo", media, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.KIND_MEDIA, captioned.Kind)
+
+	_, err = service.SendMessage(userId, chatId, "   ", nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNumberOfCalls(t, "SaveMessage", 2)
+}
+
+func TestValidateMessage(t *testing.T) {
+	service := New(new(MockStorage), nil, nil, WithCommands(echoCommand{}))
+
+	userId := uuid.New()
+	system := uuid.MustParse(utils.AIChat)
+	tests := []struct {
+		name    string
+		message utils.Message
+		status  int
+	}{
+		{"text", utils.Message{Kind: utils.KIND_TEXT, SenderID: userId, Content: "hi"}, 0},
+		{"text with media", utils.Message{Kind: utils.KIND_TEXT, SenderID: userId, Content: "hi", Media: []uuid.UUID{uuid.New()}}, http.StatusBadRequest},
+		{"media without media", utils.Message{Kind: utils.KIND_MEDIA, SenderID: userId}, http.StatusBadRequest},
+		{"unknown kind", utils.Message{Kind: "sticker", SenderID: userId, Content: "hi"}, http.StatusBadRequest},
+		{"command", utils.Message{Kind: utils.KIND_COMMAND, SenderID: userId, Payload: &utils.MessagePayload{Command: &utils.CommandPayload{Name: "echo"}}}, 0},
+		{"unknown command", utils.Message{Kind: utils.KIND_COMMAND, SenderID: userId, Payload: &utils.MessagePayload{Command: &utils.CommandPayload{Name: "nope"}}}, http.StatusBadRequest},
+		{"command without payload", utils.Message{Kind: utils.KIND_COMMAND, SenderID: userId}, http.StatusBadRequest},
+		{"payload of another kind", utils.Message{Kind: utils.KIND_TEXT, SenderID: userId, Content: "hi", Payload: &utils.MessagePayload{AI: &utils.AIPayload{}}}, http.StatusBadRequest},
+		{"system", utils.Message{Kind: utils.KIND_SYSTEM, SenderID: system, Payload: &utils.MessagePayload{System: &utils.SystemPayload{Event: utils.EVENT_MEMBER_JOINED}}}, 0},
+		{"system without event", utils.Message{Kind: utils.KIND_SYSTEM, SenderID: system, Payload: &utils.MessagePayload{System: &utils.SystemPayload{}}}, http.StatusBadRequest},
+		{"system sent by a user", utils.Message{Kind: utils.KIND_SYSTEM, SenderID: userId, Payload: &utils.MessagePayload{System: &utils.SystemPayload{Event: utils.EVENT_MEMBER_JOINED}}}, http.StatusForbidden},
+		{"ai sent by a user", utils.Message{Kind: utils.KIND_AI, SenderID: userId, Payload: &utils.MessagePayload{AI: &utils.AIPayload{}}}, http.StatusForbidden},
+		{"text sent by the system", utils.Message{Kind: utils.KIND_TEXT, SenderID: system, Content: "hi"}, http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		err := service.validateMessage(test.message)
+		if test.status == 0 {
+			assert.NoError(t, err, test.name)
+			continue
+		}
+		assert.Error(t, err, test.name)
+		assert.Equal(t, test.status, err.(*utils.ServiceError).StatusCode, test.name)
+	}
+}
+
+func TestPostSystemMessage_Payload(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	chatId := uuid.New()
+	messageId := uuid.New()
+
+	mockStorage.On("SaveMessage", mock.MatchedBy(func(m utils.Message) bool {
+		return m.Kind == utils.KIND_SYSTEM && m.Payload.System.Event == utils.EVENT_MESSAGE_PINNED &&
+			*m.Payload.System.Actor == userId && *m.Payload.System.MessageID == messageId
+	})).Return(nil)
+	mockStorage.On("UpdateChatActivity", chatId).Return(nil)
+
+	err := service.postSystemMessage(chatId, "pinned", utils.SystemPayload{Event: utils.EVENT_MESSAGE_PINNED, Actor: &userId, MessageID: &messageId})
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestUpdateMessage_Command(t *testing.T) {
+	mockStorage := new(MockStorage)
+	service := New(mockStorage, nil, nil)
+
+	userId := uuid.New()
+	original := utils.Message{ID: uuid.New(), SenderID: userId, Content: "topic", Command: "guess", Kind: utils.KIND_COMMAND}
+	mockStorage.On("GetMessage", original.ID).Return(original, nil)
+
+	_, err := service.UpdateMessage(userId, utils.Message{ID: original.ID, Content: "other topic"})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*utils.ServiceError).StatusCode)
+	mockStorage.AssertNotCalled(t, "UpdateMessage", mock.Anything)
+}
+
+func TestMessage_LegacyDocumentIsText(t *testing.T) {
+	id := uuid.New()
+	legacy, err := bson.Marshal(bson.M{"_id": id, "content": "stored before kinds", "command": ""})
+	assert.NoError(t, err)
+
+	var message utils.Message
+	assert.NoError(t, bson.Unmarshal(legacy, &message))
+	assert.Equal(t, id, message.ID)
+	assert.Equal(t, utils.KIND_TEXT, message.Kind)
+
+	// the kind of newer documents is kept
+	stored, err := bson.Marshal(utils.Message{ID: id, Kind: utils.KIND_AI, Payload: &utils.MessagePayload{AI: &utils.AIPayload{Complete: true}}})
+	assert.NoError(t, err)
+	assert.NoError(t, bson.Unmarshal(stored, &message))
+	assert.Equal(t, utils.KIND_AI, message.Kind)
+	assert.True(t, message.Payload.AI.Complete)
+}
+
+func TestRejoiningCreatorIsNotOwner(t *testing.T) {
+	creator := uuid.New()
+	newOwner := uuid.New()
+
+	// the creator left, the ownership was passed on and the role of the creator was removed
+	chat := utils.Chat{
+		ID:        uuid.New(),
+		CreatorID: creator,
+		Members:   []uuid.UUID{newOwner},
+		Roles:     map[string]utils.ChatRole{newOwner.String(): utils.ROLE_OWNER},
+	}
+
+	chat.AddMembers(creator)
+
+	assert.Equal(t, utils.ROLE_MEMBER, chat.RoleOf(creator))
+	assert.Equal(t, utils.ROLE_OWNER, chat.RoleOf(newOwner))
+}
+
+func TestUpdateChat_ReaddedCreatorIsMember(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockAuth := new(MockAuthService)
+	service := New(mockStorage, mockAuth, nil)
+
+	creator := uuid.New()
+	owner := uuid.New()
+	member := uuid.New()
+	chat := &utils.Chat{
+		ID:        uuid.New(),
+		Name:      "Team",
+		CreatorID: creator,
+		Members:   []uuid.UUID{owner, member},
+		Roles:     map[string]utils.ChatRole{owner.String(): utils.ROLE_OWNER, member.String(): utils.ROLE_MEMBER},
+	}
+
+	mockStorage.On("GetChat", chat.ID).Return(chat, nil)
+	mockAuth.On("Exists", []uuid.UUID{owner, member, creator}).Return(true, nil)
+	mockStorage.On("CreateOrUpdateChat", mock.AnythingOfType("utils.Chat")).Return(nil)
+
+	updated, err := service.UpdateChat(owner, chat.ID, "Team", []uuid.UUID{owner, member, creator})
+
+	assert.NoError(t, err)
+	assert.Equal(t, utils.ROLE_MEMBER, updated.RoleOf(creator))
+	assert.Equal(t, utils.ROLE_OWNER, updated.RoleOf(owner))
+}
@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+)
+
+// UpdateChatSettings replaces the personal settings of the user for a chat
+func (c *ChatService) UpdateChatSettings(userId uuid.UUID, settings utils.ChatSettings) (*utils.ChatSettings, error) {
+	// the ai chat has no chat document but can be personalised as well
+	if settings.ChatID != userId && !c.MemberOfChat(userId, settings.ChatID) {
+		return nil, utils.NewError("User is not a member of the chat", http.StatusUnauthorized)
+	}
+
+	now := time.Now()
+	if settings.MutedUntil != nil && !settings.MutedUntil.After(now) {
+		settings.MutedUntil = nil
+	}
+
+	settings.UserID = userId
+	settings.UpdatedAt = now
+
+	err := c.storage.SaveChatSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// applyChatSettings merges the settings of the user into the chats, filters archived chats
+// and orders the list by favourites and the custom sort order
+func (c *ChatService) applyChatSettings(userId uuid.UUID, chats []utils.Chat, opts utils.ChatListOptions) ([]utils.Chat, error) {
+	settings, err := c.storage.GetChatSettings(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	settingsByChat := make(map[uuid.UUID]utils.ChatSettings, len(settings))
+	for _, s := range settings {
+		settingsByChat[s.ChatID] = s
+	}
+
+	now := time.Now()
+	filtered := make([]utils.Chat, 0, len(chats))
+	for _, chat := range chats {
+		if s, ok := settingsByChat[chat.ID]; ok {
+			chat.Settings = &s
+			chat.Muted = s.MutedAt(now)
+		}
+
+		archived := chat.Settings != nil && chat.Settings.Archived
+		if opts.Archived != nil && *opts.Archived != archived {
+			continue
+		}
+
+		filtered = append(filtered, chat)
+	}
+
+	slices.SortStableFunc(filtered, compareChatSettings)
+	return filtered, nil
+}
+
+// compareChatSettings orders favourites first, then chats with a sort order ascending
+func compareChatSettings(a, b utils.Chat) int {
+	favourite := func(chat utils.Chat) bool { return chat.Settings != nil && chat.Settings.Favourite }
+	sortOrder := func(chat utils.Chat) *int {
+		if chat.Settings == nil {
+			return nil
+		}
+		return chat.Settings.SortOrder
+	}
+
+	if favourite(a) != favourite(b) {
+		if favourite(a) {
+			return -1
+		}
+		return 1
+	}
+
+	orderA, orderB := sortOrder(a), sortOrder(b)
+	switch {
+	case orderA != nil && orderB != nil:
+		return cmp.Compare(*orderA, *orderB)
+	case orderA != nil:
+		return -1
+	case orderB != nil:
+		return 1
+	}
+	return 0
+}
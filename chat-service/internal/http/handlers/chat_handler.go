@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"slices"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -24,6 +26,12 @@ func (c *ChatHandler) RegisterRoutes(router *mux.Router) {
 	HandleFunc(router, "/", c.createChat, "POST")
 
 	HandleFunc(router, "/version", c.getVersion, "GET")
+	HandleFunc(router, "/search", c.searchMessages, "GET")
+	HandleFunc(router, "/commands", c.getCommands, "GET")
+	HandleFunc(router, "/scheduled", c.getScheduledMessages, "GET")
+	HandleFunc(router, "/scheduled/{messageId}", c.updateScheduledMessage, "PUT")
+	HandleFunc(router, "/scheduled/{messageId}", c.cancelScheduledMessage, "DELETE")
+	HandleFunc(router, "/invites/{token}/join", c.joinChat, "POST")
 
 	HandleFunc(router, "/{chatId}", c.getChat, "GET")
 	HandleFunc(router, "/{chatId}", c.updateChat, "PUT")
@@ -31,9 +39,34 @@ func (c *ChatHandler) RegisterRoutes(router *mux.Router) {
 
 	HandleFunc(router, "/{chatId}/messages", c.getChatMessages, "GET")
 	HandleFunc(router, "/{chatId}/messages", c.sendChatMessage, "POST")
+	HandleFunc(router, "/{chatId}/read", c.readChat, "POST")
+	HandleFunc(router, "/{chatId}/members", c.addMembers, "POST")
+	HandleFunc(router, "/{chatId}/members/{userId}", c.removeMember, "DELETE")
+	HandleFunc(router, "/{chatId}/leave", c.leaveChat, "POST")
+	HandleFunc(router, "/{chatId}/admins/{userId}", c.promoteMember, "POST")
+	HandleFunc(router, "/{chatId}/admins/{userId}", c.demoteMember, "DELETE")
+	HandleFunc(router, "/{chatId}/retention", c.setRetention, "PUT")
+	HandleFunc(router, "/{chatId}/settings", c.updateChatSettings, "PUT")
+	HandleFunc(router, "/{chatId}/invites", c.getInvites, "GET")
+	HandleFunc(router, "/{chatId}/invites", c.createInvite, "POST")
+	HandleFunc(router, "/{chatId}/invites/{inviteId}", c.revokeInvite, "DELETE")
+	HandleFunc(router, "/{chatId}/export", c.exportChat, "GET")
+	HandleFunc(router, "/{chatId}/polls", c.createPoll, "POST")
+	HandleFunc(router, "/{chatId}/games/leaderboard", c.getGameLeaderboard, "GET")
+	HandleFunc(router, "/{chatId}/pins", c.getPins, "GET")
+	HandleFunc(router, "/{chatId}/pins/{messageId}", c.pinMessage, "POST")
+	HandleFunc(router, "/{chatId}/pins/{messageId}", c.unpinMessage, "DELETE")
 	HandleFunc(router, "/messages/{messageId}", c.updateChatMessage, "PUT")
 	HandleFunc(router, "/messages/{messageId}", c.deleteChatMessage, "DELETE")
 	HandleFunc(router, "/messages/{messageId}/read", c.readMessage, "GET")
+	HandleFunc(router, "/messages/{messageId}/receipts", c.getReceipts, "GET")
+	HandleFunc(router, "/messages/{messageId}/history", c.getMessageHistory, "GET")
+	HandleFunc(router, "/messages/{messageId}/thread", c.getThread, "GET")
+	HandleFunc(router, "/messages/{messageId}/reactions", c.addReaction, "POST")
+	HandleFunc(router, "/messages/{messageId}/reactions", c.removeReaction, "DELETE")
+	HandleFunc(router, "/messages/{messageId}/forward", c.forwardMessage, "POST")
+	HandleFunc(router, "/messages/{messageId}/vote", c.vote, "POST")
+	HandleFunc(router, "/messages/{messageId}/close", c.closePoll, "POST")
 
 	HandleFunc(router, "/direct-chat", c.createDirectChat, "POST")
 }
@@ -46,6 +79,93 @@ func (c *ChatHandler) getVersion(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(utils.VERSION))
 }
 
+// @Summary Search messages
+// @Description Full text search over the messages of all chats the user is a member of
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param q query string true "Search query"
+// @Param chat query string false "Only search in this chat" format(uuid)
+// @Param sender query string false "Only messages of this sender" format(uuid)
+// @Param from query string false "Only messages sent after this time" format(date-time)
+// @Param to query string false "Only messages sent before this time" format(date-time)
+// @Param has_media query bool false "Only messages with media"
+// @Param replies query bool false "Only replies"
+// @Param limit query int false "Number of results to return" default(20)
+// @Param offset query int false "Number of results to skip" default(0)
+// @Success 200 {object} utils.SearchResults "Search results"
+// @Failure 400 {object} utils.ServiceError "Invalid search query"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /search [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) searchMessages(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	params := r.URL.Query()
+	query := utils.SearchQuery{
+		Query:       params.Get("q"),
+		HasMedia:    params.Get("has_media") == "true",
+		RepliesOnly: params.Get("replies") == "true",
+	}
+
+	if chatId := params.Get("chat"); chatId != "" {
+		chatIdUUID, err := uuid.Parse(chatId)
+		if err != nil {
+			c.error(w, "Invalid chat id", http.StatusBadRequest)
+			return
+		}
+		query.ChatIDs = []uuid.UUID{chatIdUUID}
+	}
+
+	if sender := params.Get("sender"); sender != "" {
+		senderUUID, err := uuid.Parse(sender)
+		if err != nil {
+			c.error(w, "Invalid sender id", http.StatusBadRequest)
+			return
+		}
+		query.SenderID = &senderUUID
+	}
+
+	if from := params.Get("from"); from != "" {
+		parsedFrom, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			c.error(w, "Invalid from date", http.StatusBadRequest)
+			return
+		}
+		query.From = &parsedFrom
+	}
+
+	if to := params.Get("to"); to != "" {
+		parsedTo, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			c.error(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+		query.To = &parsedTo
+	}
+
+	if limitStr := params.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			query.Limit = parsedLimit
+		}
+	}
+	if offsetStr := params.Get("offset"); offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			query.Offset = parsedOffset
+		}
+	}
+
+	results, err := c.chat.SearchMessages(userId, query)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, results)
+}
+
 // @Summary Sets the status of a message to read
 // @Description returns the updated message
 // @Tags chat
@@ -53,18 +173,946 @@ func (c *ChatHandler) getVersion(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param commz-token header string true "Authenticated user JWT token"
 // @Param messageId path string true "Message ID"
-// @Success 200 {object} SendMessageRequest "Message Updated"
-// @Failure 400 {object} utils.ServiceError "Invalid request body or Chat ID"
+// @Success 200 {object} SendMessageRequest "Message Updated"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or Chat ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 404 {object} utils.ServiceError "Chat not found or message not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /messages/{messageId}/read [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) readMessage(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	messageId := mux.Vars(r)["messageId"]
+	messageUUID, err := uuid.Parse(messageId)
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := c.chat.ReadMessage(userId, messageUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	// send chat as response
+	utils.SendJsonResponse(w, updated)
+}
+
+// @Summary Marks all messages of a chat up to a message or time as read
+// @Description returns the chat_read event that is sent to the other members
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param request body ReadChatRequest false "Last seen message or time"
+// @Success 200 {object} utils.Event "Chat read"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or chat ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Message not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/read [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) readChat(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatId := mux.Vars(r)["chatId"]
+	chatIdUUID, err := uuid.Parse(chatId)
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	// the body is optional, without it the whole chat is marked as read
+	var request ReadChatRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && err != io.EOF {
+		c.error(w, "Invalid read request", http.StatusBadRequest)
+		return
+	}
+
+	event, err := c.chat.MarkChatRead(userId, chatIdUUID, request.MessageID, request.Timestamp)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, event)
+}
+
+// @Summary Add members to a group chat
+// @Description Adds users to a group chat, only admins can add members
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param request body AddMembersRequest true "Members to add"
+// @Success 200 {object} utils.Chat "Updated chat"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or chat ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User is not an admin of the chat"
+// @Failure 404 {object} utils.ServiceError "Chat not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/members [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) addMembers(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	var request AddMembersRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		c.error(w, "Invalid add members request", http.StatusBadRequest)
+		return
+	}
+
+	chat, err := c.chat.AddMembers(userId, chatIdUUID, request.Members)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, chat)
+}
+
+// @Summary Remove a member from a group chat
+// @Description Removes a member from a group chat. Admins can remove members, only the owner can remove admins
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param userId path string true "User ID of the member"
+// @Success 200 {object} utils.Chat "Updated chat"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID or user ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User is not allowed to remove that member"
+// @Failure 404 {object} utils.ServiceError "Chat or member not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/members/{userId} [delete]
+// @Security ApiKeyAuth
+func (c *ChatHandler) removeMember(w http.ResponseWriter, r *http.Request) {
+	c.memberAction(w, r, c.chat.RemoveMember)
+}
+
+// @Summary Leave a group chat
+// @Description Removes the authenticated user from a group chat. If the owner leaves, an admin or member becomes the new owner
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Success 200 {object} utils.Chat "Chat that was left"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Chat not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/leave [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) leaveChat(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	chat, err := c.chat.LeaveChat(userId, chatIdUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, chat)
+}
+
+// @Summary Promote a member to admin
+// @Description Makes a member of the chat an admin, only the owner of the chat can promote members
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param userId path string true "User ID of the member"
+// @Success 200 {object} utils.Chat "Updated chat"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID or user ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User is not the owner of the chat"
+// @Failure 404 {object} utils.ServiceError "Chat or member not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/admins/{userId} [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) promoteMember(w http.ResponseWriter, r *http.Request) {
+	c.memberAction(w, r, c.chat.PromoteMember)
+}
+
+// @Summary Demote an admin to member
+// @Description Makes an admin of the chat a plain member again, only the owner of the chat can demote admins
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param userId path string true "User ID of the admin"
+// @Success 200 {object} utils.Chat "Updated chat"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID or user ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User is not the owner of the chat"
+// @Failure 404 {object} utils.ServiceError "Chat or member not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/admins/{userId} [delete]
+// @Security ApiKeyAuth
+func (c *ChatHandler) demoteMember(w http.ResponseWriter, r *http.Request) {
+	c.memberAction(w, r, c.chat.DemoteMember)
+}
+
+// memberAction runs an action of the user on another member of the chat
+func (c *ChatHandler) memberAction(w http.ResponseWriter, r *http.Request, action func(userId, chatId, memberId uuid.UUID) (*utils.Chat, error)) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id and member id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	memberUUID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		c.error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	chat, err := action(userId, chatIdUUID, memberUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, chat)
+}
+
+// @Summary Get available commands
+// @Description Returns the slash commands that can be sent with a message, ordered by name
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Success 200 {array} utils.CommandInfo "Available commands"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Router /commands [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) getCommands(w http.ResponseWriter, r *http.Request) {
+	utils.SendJsonResponse(w, c.chat.GetCommands())
+}
+
+// @Summary Get scheduled messages
+// @Description Returns the scheduled messages of the authenticated user that have not been sent yet, including messages that failed to send
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Success 200 {array} utils.ScheduledMessage "Scheduled messages"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /scheduled [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) getScheduledMessages(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	messages, err := c.chat.GetScheduledMessages(userId)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, messages)
+}
+
+// @Summary Update a scheduled message
+// @Description Changes the content or the time of a scheduled message. A message that failed to send is scheduled again
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param messageId path string true "Scheduled message ID"
+// @Param request body UpdateScheduledMessageRequest true "Updated message"
+// @Success 200 {object} utils.ScheduledMessage "Updated scheduled message"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or message ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 404 {object} utils.ServiceError "Scheduled message not found"
+// @Failure 409 {object} utils.ServiceError "Message is already being sent"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /scheduled/{messageId} [put]
+// @Security ApiKeyAuth
+func (c *ChatHandler) updateScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get message id from request
+	messageUUID, err := uuid.Parse(mux.Vars(r)["messageId"])
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	var request UpdateScheduledMessageRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		c.error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	message, err := c.chat.UpdateScheduledMessage(userId, messageUUID, request.Message, request.Media, request.SendAt)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, message)
+}
+
+// @Summary Cancel a scheduled message
+// @Description Deletes a scheduled message before it is sent and returns it
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param messageId path string true "Scheduled message ID"
+// @Success 200 {object} utils.ScheduledMessage "Cancelled scheduled message"
+// @Failure 400 {object} utils.ServiceError "Invalid message ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 404 {object} utils.ServiceError "Scheduled message not found"
+// @Failure 409 {object} utils.ServiceError "Message is already being sent"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /scheduled/{messageId} [delete]
+// @Security ApiKeyAuth
+func (c *ChatHandler) cancelScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get message id from request
+	messageUUID, err := uuid.Parse(mux.Vars(r)["messageId"])
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	message, err := c.chat.CancelScheduledMessage(userId, messageUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, message)
+}
+
+// @Summary Change the message retention of a chat
+// @Description Sets after how many days or seconds messages of the chat are deleted, only admins can change it. Zero values keep messages forever
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param request body RetentionRequest true "Retention policy"
+// @Success 200 {object} utils.Chat "Updated chat"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or chat ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User is not an admin of the chat"
+// @Failure 404 {object} utils.ServiceError "Chat not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/retention [put]
+// @Security ApiKeyAuth
+func (c *ChatHandler) setRetention(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	var request RetentionRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		c.error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	retention := utils.Retention{Days: request.Days, MessageTTL: request.MessageTTL}
+	chat, err := c.chat.SetRetention(userId, chatIdUUID, retention)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, chat)
+}
+
+// @Summary Create an invite link
+// @Description Creates a signed invite token for a group chat with an optional expiry and usage limit, every member can create invites
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param request body CreateInviteRequest false "Invite options"
+// @Success 200 {object} utils.Invite "Created invite with its token"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or chat ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Chat not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/invites [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) createInvite(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	// all options are optional, so an empty body is allowed
+	var request CreateInviteRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && err != io.EOF {
+		c.error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	invite, err := c.chat.CreateInvite(userId, chatIdUUID, request.ExpiresAt, request.MaxUses)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, invite)
+}
+
+// @Summary Get the invites of a chat
+// @Description Returns the invites of the chat that can still be used
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Success 200 {array} utils.Invite "Active invites"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Chat not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/invites [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) getInvites(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	invites, err := c.chat.GetInvites(userId, chatIdUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, invites)
+}
+
+// @Summary Revoke an invite
+// @Description Invalidates an invite, only the member who created it and admins can revoke it. Returns the remaining active invites
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param inviteId path string true "Invite ID"
+// @Success 200 {array} utils.Invite "Remaining active invites"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID or invite ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not allowed to revoke the invite"
+// @Failure 404 {object} utils.ServiceError "Chat or invite not found"
+// @Failure 409 {object} utils.ServiceError "Invite already revoked"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/invites/{inviteId} [delete]
+// @Security ApiKeyAuth
+func (c *ChatHandler) revokeInvite(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id and invite id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	inviteUUID, err := uuid.Parse(mux.Vars(r)["inviteId"])
+	if err != nil {
+		c.error(w, "Invalid invite id", http.StatusBadRequest)
+		return
+	}
+
+	err = c.chat.RevokeInvite(userId, chatIdUUID, inviteUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	invites, err := c.chat.GetInvites(userId, chatIdUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, invites)
+}
+
+// @Summary Join a chat with an invite
+// @Description Adds the authenticated user to the chat of the invite. Members of the chat do not use up the invite
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param token path string true "Invite token"
+// @Success 200 {object} utils.Chat "Joined chat"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 404 {object} utils.ServiceError "Invalid invite"
+// @Failure 410 {object} utils.ServiceError "Invite expired, revoked or used up"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /invites/{token}/join [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) joinChat(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	chat, err := c.chat.JoinChat(userId, mux.Vars(r)["token"])
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, chat)
+}
+
+// @Summary Update the personal settings of a chat
+// @Description Replaces the settings of the authenticated user for the chat. Muted chats still count unread messages but are flagged as muted
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param request body ChatSettingsRequest true "Chat settings"
+// @Success 200 {object} utils.ChatSettings "Updated settings"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or chat ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/settings [put]
+// @Security ApiKeyAuth
+func (c *ChatHandler) updateChatSettings(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	var request ChatSettingsRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		c.error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := c.chat.UpdateChatSettings(userId, utils.ChatSettings{
+		ChatID:     chatIdUUID,
+		MutedUntil: request.MutedUntil,
+		Archived:   request.Archived,
+		Favourite:  request.Favourite,
+		SortOrder:  request.SortOrder,
+	})
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, settings)
+}
+
+// @Summary Export the history of a chat
+// @Description Streams the full history of the chat as JSON, Markdown or HTML with sender names, media references, reply links and deleted messages
+// @Tags chat
+// @Produce json
+// @Produce text/markdown
+// @Produce text/html
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param format query string false "Export format: json (default), markdown or html"
+// @Success 200 {file} file "Chat export"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID or format"
+// @Failure 401 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Chat not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/export [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) exportChat(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	export, err := c.chat.ExportChat(userId, chatIdUUID, r.URL.Query().Get("format"))
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.FileName()))
+
+	// the status is already sent, errors can only be logged
+	err = export.Write(w)
+	if err != nil {
+		logger.Err(err).Str("chat", chatIdUUID.String()).Msg("error while exporting chat")
+	}
+}
+
+// @Summary Send a poll
+// @Description Sends a poll to the chat, the question is used as the content of the message
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param request body CreatePollRequest true "Poll"
+// @Success 200 {object} utils.Message "Poll message"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or chat ID"
+// @Failure 401 {object} utils.ServiceError "User not member of chat"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/polls [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) createPoll(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	var request CreatePollRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		c.error(w, "Invalid poll", http.StatusBadRequest)
+		return
+	}
+
+	poll := utils.Poll{
+		Question:       request.Question,
+		MultipleChoice: request.MultipleChoice,
+		Anonymous:      request.Anonymous,
+		ClosesAt:       request.ClosesAt,
+	}
+	for _, option := range request.Options {
+		poll.Options = append(poll.Options, utils.PollOption{Text: option})
+	}
+
+	message, err := c.chat.CreatePoll(userId, chatIdUUID, poll)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, message)
+}
+
+// @Summary Get the guessing game leaderboard of a chat
+// @Description Returns the all-time scores of the guessing games played in the chat, the best player first
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Success 200 {array} utils.GameScore "Leaderboard"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID"
+// @Failure 401 {object} utils.ServiceError "User not member of chat"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/games/leaderboard [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) getGameLeaderboard(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	scores, err := c.chat.GetGameLeaderboard(userId, chatIdUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, scores)
+}
+
+// @Summary Get the pinned messages of a chat
+// @Description Returns the pinned messages of the chat, the latest pin first
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Success 200 {array} utils.PinnedMessage "Pinned messages"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Chat not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/pins [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) getPins(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get chat id from request
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	pins, err := c.chat.GetPins(userId, chatIdUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, pins)
+}
+
+// @Summary Pin a message
+// @Description Pins a message of the chat, every member can pin messages up to the configured limit per chat
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param messageId path string true "Message ID"
+// @Success 200 {object} utils.Pin "Created pin"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID or message ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Chat or message not found"
+// @Failure 409 {object} utils.ServiceError "Message already pinned or pin limit reached"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/pins/{messageId} [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) pinMessage(w http.ResponseWriter, r *http.Request) {
+	userId, chatIdUUID, messageUUID, ok := c.pinVars(w, r)
+	if !ok {
+		return
+	}
+
+	pin, err := c.chat.PinMessage(userId, chatIdUUID, messageUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, pin)
+}
+
+// @Summary Unpin a message
+// @Description Removes a pin, only the member who pinned the message and admins can remove it. Returns the remaining pins
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param chatId path string true "Chat ID"
+// @Param messageId path string true "Message ID"
+// @Success 200 {array} utils.PinnedMessage "Remaining pinned messages"
+// @Failure 400 {object} utils.ServiceError "Invalid chat ID or message ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not allowed to remove the pin"
+// @Failure 404 {object} utils.ServiceError "Chat not found or message not pinned"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /{chatId}/pins/{messageId} [delete]
+// @Security ApiKeyAuth
+func (c *ChatHandler) unpinMessage(w http.ResponseWriter, r *http.Request) {
+	userId, chatIdUUID, messageUUID, ok := c.pinVars(w, r)
+	if !ok {
+		return
+	}
+
+	err := c.chat.UnpinMessage(userId, chatIdUUID, messageUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	pins, err := c.chat.GetPins(userId, chatIdUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, pins)
+}
+
+// pinVars reads the user, chat id and message id of a pin request
+func (c *ChatHandler) pinVars(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, uuid.UUID, bool) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	chatIdUUID, err := uuid.Parse(mux.Vars(r)["chatId"])
+	if err != nil {
+		c.error(w, "Invalid chat id", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	messageUUID, err := uuid.Parse(mux.Vars(r)["messageId"])
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	return userId, chatIdUUID, messageUUID, true
+}
+
+// @Summary Get the read receipts of a message
+// @Description returns for every member except the sender if and when the message was read
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param messageId path string true "Message ID"
+// @Success 200 {array} utils.Receipt "Read receipts"
+// @Failure 400 {object} utils.ServiceError "Invalid message ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Message not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /messages/{messageId}/receipts [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) getReceipts(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get message id from request
+	messageId := mux.Vars(r)["messageId"]
+	messageUUID, err := uuid.Parse(messageId)
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	receipts, err := c.chat.GetReceipts(userId, messageUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, receipts)
+}
+
+// @Summary Get the edit history of a message
+// @Description returns the current version of the message and all previous versions in chronological order
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param messageId path string true "Message ID"
+// @Success 200 {object} utils.MessageHistory "Message history"
+// @Failure 400 {object} utils.ServiceError "Invalid message ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Message not found"
+// @Failure 410 {object} utils.ServiceError "Message has been deleted"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /messages/{messageId}/history [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) getMessageHistory(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get message id from request
+	messageId := mux.Vars(r)["messageId"]
+	messageUUID, err := uuid.Parse(messageId)
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	history, err := c.chat.GetHistory(userId, messageUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, history)
+}
+
+// @Summary Get the thread of a message
+// @Description returns the root message of the thread followed by all replies in chronological order
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param messageId path string true "Message ID"
+// @Success 200 {array} utils.Message "Thread messages"
+// @Failure 400 {object} utils.ServiceError "Invalid message ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Message not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /messages/{messageId}/thread [get]
+// @Security ApiKeyAuth
+func (c *ChatHandler) getThread(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get message id from request
+	messageId := mux.Vars(r)["messageId"]
+	messageUUID, err := uuid.Parse(messageId)
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	thread, err := c.chat.GetThread(userId, messageUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, thread)
+}
+
+// @Summary React to a message with an emoji
+// @Description adds the emoji reaction of the user and returns the updated message
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param messageId path string true "Message ID"
+// @Param request body ReactionRequest true "Reaction"
+// @Success 200 {object} utils.Message "Message Updated"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or message ID"
 // @Failure 401 {object} utils.ServiceError "Unauthorized"
-// @Failure 404 {object} utils.ServiceError "Chat not found or message not found"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Message not found"
 // @Failure 500 {object} utils.ServiceError "Internal server error"
-// @Router /messages/{messageId}/read [get]
+// @Router /messages/{messageId}/reactions [post]
 // @Security ApiKeyAuth
-func (c *ChatHandler) readMessage(w http.ResponseWriter, r *http.Request) {
+func (c *ChatHandler) addReaction(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value("user-id")
 	userId := uuid.MustParse(user.(string))
 
-	// get chat id from request
+	// get message id from request
 	messageId := mux.Vars(r)["messageId"]
 	messageUUID, err := uuid.Parse(messageId)
 	if err != nil {
@@ -72,12 +1120,173 @@ func (c *ChatHandler) readMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updated, err := c.chat.ReadMessage(userId, messageUUID)
+	// parse reaction
+	var reaction ReactionRequest
+	err = json.NewDecoder(r.Body).Decode(&reaction)
+	if err != nil {
+		c.error(w, "Invalid reaction", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := c.chat.ReactToMessage(userId, messageUUID, reaction.Emoji)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, updated)
+}
+
+// @Summary Forward a message to other chats
+// @Description Sends a copy of the message with its media to every target chat, the copies reference the original sender, chat and time
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param messageId path string true "Message ID"
+// @Param request body ForwardMessageRequest true "Target chats"
+// @Success 200 {array} utils.Message "Forwarded messages"
+// @Failure 400 {object} utils.ServiceError "Invalid request body or message ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of the source or a target chat"
+// @Failure 404 {object} utils.ServiceError "Message not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /messages/{messageId}/forward [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) forwardMessage(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get message id from request
+	messageUUID, err := uuid.Parse(mux.Vars(r)["messageId"])
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	var request ForwardMessageRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		c.error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	forwarded, err := c.chat.ForwardMessage(userId, messageUUID, request.ChatIDs)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, forwarded)
+}
+
+// @Summary Vote in a poll
+// @Description Counts the vote of the user and returns the updated poll message, every member can vote once
+// @Tags chat
+// @Accept json
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param messageId path string true "Message ID"
+// @Param request body VoteRequest true "Vote"
+// @Success 200 {object} utils.Message "Poll message"
+// @Failure 400 {object} utils.ServiceError "Invalid request body, message ID or options"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Message not found"
+// @Failure 409 {object} utils.ServiceError "Already voted or poll closed"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /messages/{messageId}/vote [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) vote(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get message id from request
+	messageUUID, err := uuid.Parse(mux.Vars(r)["messageId"])
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	var request VoteRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		c.error(w, "Invalid vote", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := c.chat.Vote(userId, messageUUID, request.Options)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, updated)
+}
+
+// @Summary Close a poll
+// @Description Stops the voting and freezes the results, only the creator of the poll and admins can close it
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param messageId path string true "Message ID"
+// @Success 200 {object} utils.Message "Poll message"
+// @Failure 400 {object} utils.ServiceError "Invalid message ID or message is not a poll"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "Not allowed to close the poll"
+// @Failure 404 {object} utils.ServiceError "Message not found"
+// @Failure 409 {object} utils.ServiceError "Poll already closed"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /messages/{messageId}/close [post]
+// @Security ApiKeyAuth
+func (c *ChatHandler) closePoll(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get message id from request
+	messageUUID, err := uuid.Parse(mux.Vars(r)["messageId"])
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := c.chat.ClosePoll(userId, messageUUID)
+	if c.handleErrors(err, w) {
+		return
+	}
+
+	utils.SendJsonResponse(w, updated)
+}
+
+// @Summary Remove a reaction from a message
+// @Description removes the emoji reaction of the user and returns the updated message
+// @Tags chat
+// @Produce json
+// @Param commz-token header string true "Authenticated user JWT token"
+// @Param messageId path string true "Message ID"
+// @Param emoji query string true "Emoji to remove"
+// @Success 200 {object} utils.Message "Message Updated"
+// @Failure 400 {object} utils.ServiceError "Invalid emoji or message ID"
+// @Failure 401 {object} utils.ServiceError "Unauthorized"
+// @Failure 403 {object} utils.ServiceError "User not member of chat"
+// @Failure 404 {object} utils.ServiceError "Message not found"
+// @Failure 500 {object} utils.ServiceError "Internal server error"
+// @Router /messages/{messageId}/reactions [delete]
+// @Security ApiKeyAuth
+func (c *ChatHandler) removeReaction(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user-id")
+	userId := uuid.MustParse(user.(string))
+
+	// get message id from request
+	messageId := mux.Vars(r)["messageId"]
+	messageUUID, err := uuid.Parse(messageId)
+	if err != nil {
+		c.error(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := c.chat.RemoveReaction(userId, messageUUID, r.URL.Query().Get("emoji"))
 	if c.handleErrors(err, w) {
 		return
 	}
 
-	// send chat as response
 	utils.SendJsonResponse(w, updated)
 }
 
@@ -215,7 +1424,7 @@ func (c *ChatHandler) getChat(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Delete a chat
-// @Description Deletes an exsisting chat, the user has to be part of that chat.
+// @Description Deletes an exsisting chat, only the owner can delete group chats.
 // @Tags chat
 // @Accept json
 // @Produce json
@@ -251,7 +1460,7 @@ func (c *ChatHandler) deleteChat(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Updates a chat between users
-// @Description Updates achat between the authenticated user other users
+// @Description Updates achat between the authenticated user other users, only admins can update a chat
 // @Tags chat
 // @Accept json
 // @Produce json
@@ -393,10 +1602,13 @@ func (c *ChatHandler) createChat(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Get user's chats
-// @Description Retrieves all chats (both direct and group) that the authenticated user is a member of
+// @Description Retrieves all chats (both direct and group) that the authenticated user is a member of, including the unread count, last message and last read time of the user.
+// @Description The personal settings of the user are included, favourites come first followed by chats with a sort order
 // @Tags chat
 // @Produce json
 // @Param commz-token header string true "Authenticated user JWT token"
+// @Param messages query bool false "Embed the latest messages of every chat" default(true)
+// @Param archived query bool false "Only list archived chats if true or chats that are not archived if false"
 // @Success 200 {array} utils.Chat "List of chats"
 // @Failure 401 {object} utils.ServiceError "Unauthorized"
 // @Failure 500 {object} utils.ServiceError "Internal server error"
@@ -406,7 +1618,23 @@ func (c *ChatHandler) getChats(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value("user-id")
 	userId := uuid.MustParse(user.(string))
 
-	chats, err := c.chat.GetChats(userId)
+	opts := utils.ChatListOptions{IncludeMessages: true}
+	if messagesStr := r.URL.Query().Get("messages"); messagesStr != "" {
+		if includeMessages, err := strconv.ParseBool(messagesStr); err == nil {
+			opts.IncludeMessages = includeMessages
+		}
+	}
+
+	if archivedStr := r.URL.Query().Get("archived"); archivedStr != "" {
+		archived, err := strconv.ParseBool(archivedStr)
+		if err != nil {
+			c.error(w, "Invalid archived filter", http.StatusBadRequest)
+			return
+		}
+		opts.Archived = &archived
+	}
+
+	chats, err := c.chat.GetChats(userId, opts)
 
 	if c.handleErrors(err, w) {
 		return
@@ -417,13 +1645,19 @@ func (c *ChatHandler) getChats(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Get chat messages
-// @Description Retrieves all messages from a specific chat
+// @Description Retrieves all messages from a specific chat.
+// @Description If before, after or around is set, a utils.MessagePage with the cursors of the neighbouring pages is returned instead of a list.
+// @Description Set cursor to true to get the first page, starting at the latest message, as a utils.MessagePage as well
 // @Tags chat
 // @Produce json
 // @Param commz-token header string true "Authenticated user JWT token"
 // @Param chatId path string true "Chat ID" format(uuid)
 // @Param limit query int false "Number of messages to return" default(50)
 // @Param offset query int false "Number of messages to skip" default(0)
+// @Param before query string false "Cursor or message ID, returns the messages before it"
+// @Param after query string false "Cursor or message ID, returns the messages after it"
+// @Param around query string false "Message ID, returns the messages around it including the message itself" format(uuid)
+// @Param cursor query bool false "Return the latest messages as a utils.MessagePage with cursors instead of a list" default(false)
 // @Success 200 {array} utils.Message "List of chat messages"
 // @Failure 400 {object} utils.ServiceError "Invalid chat ID"
 // @Failure 401 {object} utils.ServiceError "Unauthorized"
@@ -453,6 +1687,35 @@ func (c *ChatHandler) getChatMessages(w http.ResponseWriter, r *http.Request) {
 			limit = parsedLimit
 		}
 	}
+
+	// cursor based pagination
+	pageQuery := utils.MessagePageQuery{
+		Before: r.URL.Query().Get("before"),
+		After:  r.URL.Query().Get("after"),
+		Limit:  limit,
+	}
+	if around := r.URL.Query().Get("around"); around != "" {
+		aroundUUID, err := uuid.Parse(around)
+		if err != nil {
+			c.error(w, "Invalid message id", http.StatusBadRequest)
+			return
+		}
+		pageQuery.Around = &aroundUUID
+	}
+
+	// the list with offsets is kept for clients that do not use cursors yet
+	cursor, _ := strconv.ParseBool(r.URL.Query().Get("cursor"))
+
+	if cursor || pageQuery.Around != nil || pageQuery.Before != "" || pageQuery.After != "" {
+		page, err := c.chat.GetMessagesPage(userId, chatIdUUID, pageQuery)
+		if c.handleErrors(err, w) {
+			return
+		}
+
+		utils.SendJsonResponse(w, page)
+		return
+	}
+
 	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
 		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
 			offset = parsedOffset
@@ -470,14 +1733,14 @@ func (c *ChatHandler) getChatMessages(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Send chat message
-// @Description Sends a new message to a specific chat
+// @Description Sends a new message to a specific chat. With send_at the message is scheduled and sent at that time. The kind and payload of the message are set by the server
 // @Tags chat
 // @Accept json
 // @Produce json
 // @Param commz-token header string true "Authenticated user JWT token"
 // @Param chatId path string true "Chat ID" format(uuid)
 // @Param request body SendMessageRequest true "Message content"
-// @Success 200 {object} utils.Message "Message sent successfully"
+// @Success 200 {object} utils.Message "Message sent successfully, a utils.ScheduledMessage if send_at is set"
 // @Failure 400 {object} utils.ServiceError "Invalid request body or chat ID"
 // @Failure 401 {object} utils.ServiceError "Unauthorized"
 // @Failure 403 {object} utils.ServiceError "User not member of chat"
@@ -508,12 +1771,28 @@ func (c *ChatHandler) sendChatMessage(w http.ResponseWriter, r *http.Request) {
 
 	// parse message
 	var message SendMessageRequest
+	// commands may be sent without arguments
 	err = json.Unmarshal(b, &message)
-	if err != nil || message.Message == "" && len(message.Media) == 0 {
+	if err != nil || message.Message == "" && len(message.Media) == 0 && message.Command == "" {
 		c.error(w, "Invalid message", http.StatusBadRequest)
 		return
 	}
 
+	if message.SendAt != nil {
+		if message.Command != "" {
+			c.error(w, "Commands can not be scheduled", http.StatusBadRequest)
+			return
+		}
+
+		scheduled, err := c.chat.ScheduleMessage(userId, chatIdUUID, message.Message, message.Media, message.ReplyTo, *message.SendAt)
+		if c.handleErrors(err, w) {
+			return
+		}
+
+		utils.SendJsonResponse(w, scheduled)
+		return
+	}
+
 	if message.Command != "" {
 		// handle command
 		messageResult, err := c.chat.Command(userId, chatIdUUID, message.Message, message.Command)
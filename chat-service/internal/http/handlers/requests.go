@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -28,7 +30,9 @@ type CreateChatRequest struct {
 	Message *string `json:"message"`
 }
 
-// SendMessageRequest represents the request body for sending a message
+// SendMessageRequest represents the request body for sending a message.
+// The kind and payload of the message are set by the server, messages with media
+// are media messages and all others are text messages
 type SendMessageRequest struct {
 	// The message content
 	// required: true
@@ -37,4 +41,96 @@ type SendMessageRequest struct {
 	Media   []uuid.UUID `json:"media"`
 	Command string      `json:"command"`
 	ReplyTo *uuid.UUID  `json:"reply_to"`
+	// If set, the message is scheduled and sent at that time
+	SendAt *time.Time `json:"send_at"`
+}
+
+// UpdateScheduledMessageRequest represents the request body for changing a scheduled message
+type UpdateScheduledMessageRequest struct {
+	// The message content
+	Message string      `json:"message"`
+	Media   []uuid.UUID `json:"media"`
+	// The time the message is sent at
+	// required: true
+	SendAt time.Time `json:"send_at"`
+}
+
+// ReactionRequest represents the request body for reacting to a message
+type ReactionRequest struct {
+	// The emoji to react with
+	// required: true
+	Emoji string `json:"emoji"`
+}
+
+// CreatePollRequest represents the request body for sending a poll
+type CreatePollRequest struct {
+	// The question of the poll
+	// required: true
+	Question string `json:"question"`
+	// The options to vote for
+	// required: true
+	Options []string `json:"options"`
+	// Users can vote for several options
+	MultipleChoice bool `json:"multiple_choice"`
+	// Only the number of votes is shown, not who voted
+	Anonymous bool `json:"anonymous"`
+	// The time the poll closes, polls without a close time are open until they are closed
+	ClosesAt *time.Time `json:"closes_at"`
+}
+
+// VoteRequest represents the request body for voting in a poll
+type VoteRequest struct {
+	// The indexes of the chosen options
+	// required: true
+	Options []int `json:"options"`
+}
+
+// ForwardMessageRequest represents the request body for forwarding a message to other chats
+type ForwardMessageRequest struct {
+	// The chats the message is forwarded to
+	// required: true
+	ChatIDs []uuid.UUID `json:"chat_ids"`
+}
+
+// ReadChatRequest represents the request body for marking a chat as read.
+// If neither a message nor a timestamp is given, every message is marked as read
+type ReadChatRequest struct {
+	// The last message the user has seen
+	MessageID *uuid.UUID `json:"message_id"`
+	// The time up to which all messages have been seen
+	Timestamp *time.Time `json:"timestamp"`
+}
+
+// AddMembersRequest represents the request body for adding members to a group chat
+type AddMembersRequest struct {
+	// List of user IDs to add to the chat
+	// required: true
+	Members []uuid.UUID `json:"members"`
+}
+
+// RetentionRequest represents the request body for changing how long messages of a chat are kept.
+// If both values are zero, messages are kept forever
+type RetentionRequest struct {
+	// Days after which messages are deleted
+	Days int `json:"days"`
+	// Seconds after which each message disappears
+	MessageTTL int `json:"message_ttl"`
+}
+
+// CreateInviteRequest represents the request body for creating an invite link
+type CreateInviteRequest struct {
+	// The time the invite expires, invites without expiry are valid until they are revoked
+	ExpiresAt *time.Time `json:"expires_at"`
+	// How often the invite can be used, zero allows unlimited uses
+	MaxUses int `json:"max_uses"`
+}
+
+// ChatSettingsRequest represents the request body for changing the personal settings of a chat
+type ChatSettingsRequest struct {
+	// Notifications are muted until that time
+	MutedUntil *time.Time `json:"muted_until"`
+	Archived   bool       `json:"archived"`
+	Favourite  bool       `json:"favourite"`
+	// Position of the chat in the chat list
+	SortOrder *int `json:"sort_order"`
 }
@@ -0,0 +1,309 @@
+package importer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	SOURCE_WHATSAPP = "whatsapp"
+	SOURCE_SLACK    = "slack"
+	// messages are written in batches, so large histories do not need one huge request
+	IMPORT_BATCH_SIZE = 500
+)
+
+var (
+	logger = utils.GetLogger("import")
+	// every imported chat and message gets an id derived from its origin,
+	// so importing the same export again finds the documents of the last run
+	importNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://commz/import"))
+)
+
+// ImportedChat is a chat parsed from an export, before its participants are mapped to users
+type ImportedChat struct {
+	Source string
+	// Key identifies the chat within the source, e.g. the id of a Slack channel
+	Key      string
+	Name     string
+	Messages []ImportedMessage
+}
+
+// ImportedMessage is a message parsed from an export
+type ImportedMessage struct {
+	// Key identifies the message within the chat, messages without a key are identified by their content
+	Key string
+	// Author is the name shown in the export, Email is only set if the export contains it.
+	// AuthorID identifies the author within the source, exports without ids use the name
+	Author    string
+	AuthorID  string
+	Email     string
+	Timestamp time.Time
+	Content   string
+	// ReplyTo is the key of the message this message replies to
+	ReplyTo string
+}
+
+// Options control how the participants of an export become members of the chat
+type Options struct {
+	// Emails maps author ids or names to emails for exports without emails
+	Emails map[string]string
+	// Members are added to the chat in addition to the mapped participants
+	Members []string
+	// Owner becomes the owner of a new chat, the first member is used if it is empty
+	Owner string
+}
+
+// Result summarizes an import
+type Result struct {
+	ChatID   uuid.UUID
+	Name     string
+	Messages int
+	Imported int64
+	// Unmapped lists the authors that are not users, their messages are imported as system messages.
+	// Once an author is mapped in a later run, their messages are moved to the user
+	Unmapped []string
+}
+
+type Importer struct {
+	storage utils.Storage
+	auth    utils.AuthService
+}
+
+func New(storage utils.Storage, auth utils.AuthService) Importer {
+	return Importer{
+		storage: storage,
+		auth:    auth,
+	}
+}
+
+// Import creates the chat if it does not exist yet and inserts all messages that were not imported before
+func (i *Importer) Import(imported ImportedChat, opts Options) (*Result, error) {
+	if len(imported.Messages) == 0 {
+		return nil, fmt.Errorf("%s contains no messages", imported.Name)
+	}
+
+	users, err := i.mapAuthors(imported, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []uuid.UUID{}
+	unmapped := []string{}
+	for _, message := range imported.Messages {
+		user, ok := users[message.authorKey()]
+		if !ok {
+			if !slices.Contains(unmapped, message.Author) {
+				unmapped = append(unmapped, message.Author)
+			}
+			continue
+		}
+		if !slices.Contains(members, user.ID) {
+			members = append(members, user.ID)
+		}
+	}
+
+	extra, err := i.users(append([]string{opts.Owner}, opts.Members...))
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range extra {
+		if !slices.Contains(members, user.ID) {
+			members = append(members, user.ID)
+		}
+	}
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("none of the participants of %s is a user, map them with their emails", imported.Name)
+	}
+
+	owner := members[0]
+	if opts.Owner != "" {
+		user, ok := extra[strings.ToLower(opts.Owner)]
+		if !ok {
+			return nil, fmt.Errorf("owner %s is not a user", opts.Owner)
+		}
+		owner = user.ID
+	}
+
+	chat, err := i.saveChat(imported, members, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := toMessages(chat.ID, imported.Messages, users)
+
+	var inserted int64
+	for start := 0; start < len(messages); start += IMPORT_BATCH_SIZE {
+		end := min(start+IMPORT_BATCH_SIZE, len(messages))
+		count, err := i.storage.ImportMessages(messages[start:end])
+		if err != nil {
+			return nil, err
+		}
+		inserted += count
+		logger.Debug().Str("chat", chat.ID.String()).Int("messages", end).Msg("imported batch")
+	}
+
+	return &Result{
+		ChatID:   chat.ID,
+		Name:     chat.Name,
+		Messages: len(messages),
+		Imported: inserted,
+		Unmapped: unmapped,
+	}, nil
+}
+
+// authorKey tells authors apart, different people can have the same name
+func (m ImportedMessage) authorKey() string {
+	if m.AuthorID != "" {
+		return m.AuthorID
+	}
+	return m.Author
+}
+
+// mapAuthors finds the user of every author by the email of the export or of the options
+func (i *Importer) mapAuthors(imported ImportedChat, opts Options) (map[string]utils.User, error) {
+	emails := map[string]string{}
+	for _, message := range imported.Messages {
+		email := message.Email
+		if email == "" {
+			email = opts.Emails[message.authorKey()]
+		}
+		if email == "" {
+			email = opts.Emails[message.Author]
+		}
+		if email != "" {
+			emails[message.authorKey()] = strings.ToLower(email)
+		}
+	}
+
+	found, err := i.users(mapValues(emails))
+	if err != nil {
+		return nil, err
+	}
+
+	users := map[string]utils.User{}
+	for author, email := range emails {
+		if user, ok := found[email]; ok {
+			users[author] = user
+		}
+	}
+	return users, nil
+}
+
+func (i *Importer) users(emails []string) (map[string]utils.User, error) {
+	emails = slices.DeleteFunc(slices.Clone(emails), func(email string) bool { return email == "" })
+	if len(emails) == 0 {
+		return map[string]utils.User{}, nil
+	}
+	return i.auth.GetUsersByEmail(emails...)
+}
+
+// saveChat creates the chat of the import, a chat of an earlier run keeps its settings and members
+func (i *Importer) saveChat(imported ImportedChat, members []uuid.UUID, owner uuid.UUID) (*utils.Chat, error) {
+	chatId := uuid.NewSHA1(importNamespace, []byte(imported.Source+":"+imported.Key))
+	lastActive := imported.Messages[len(imported.Messages)-1].Timestamp
+
+	chat, err := i.storage.GetChat(chatId)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	if chat == nil {
+		chat = &utils.Chat{
+			ID:         chatId,
+			Name:       imported.Name,
+			CreatorID:  owner,
+			CreatedAt:  imported.Messages[0].Timestamp,
+			LastActive: lastActive,
+			Roles:      map[string]utils.ChatRole{owner.String(): utils.ROLE_OWNER},
+		}
+	}
+
+	chat.AddMembers(members...)
+
+	if lastActive.After(chat.LastActive) {
+		chat.LastActive = lastActive
+	}
+
+	err = i.storage.CreateOrUpdateChat(*chat)
+	if err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+// toMessages converts the parsed messages, authors without a user are kept in the content.
+// The root message of every thread gets the number and the time of its replies
+func toMessages(chatId uuid.UUID, imported []ImportedMessage, users map[string]utils.User) []utils.Message {
+	occurrences := map[string]int{}
+	roots := map[uuid.UUID]int{}
+	messages := make([]utils.Message, 0, len(imported))
+	for _, message := range imported {
+		key := message.Key
+		if key == "" {
+			// identical messages at the same time are told apart by their position
+			key = fmt.Sprintf("%s|%d|%s", message.Author, message.Timestamp.UnixNano(), message.Content)
+			occurrences[key]++
+			key = fmt.Sprintf("%s|%d", key, occurrences[key])
+		}
+
+		sender := uuid.MustParse(utils.AIChat)
+		content := message.Content
+		if user, ok := users[message.authorKey()]; ok {
+			sender = user.ID
+		} else {
+			content = fmt.Sprintf("**%s:** %s", message.Author, content)
+		}
+
+		converted := utils.Message{
+			ID:        uuid.NewSHA1(chatId, []byte(key)),
+			ChatID:    chatId,
+			SenderID:  sender,
+			Content:   content,
+			Timestamp: message.Timestamp,
+			UpdatedAt: message.Timestamp,
+			Media:     []uuid.UUID{},
+			Read:      true,
+			Kind:      utils.KIND_TEXT,
+		}
+
+		if message.ReplyTo != "" {
+			parent := uuid.NewSHA1(chatId, []byte(message.ReplyTo))
+			converted.ReplyTo = &parent
+			converted.ThreadID = &parent
+		}
+
+		roots[converted.ID] = len(messages)
+		messages = append(messages, converted)
+	}
+
+	for _, message := range messages {
+		if message.ThreadID == nil {
+			continue
+		}
+		root, ok := roots[*message.ThreadID]
+		if !ok {
+			continue
+		}
+		messages[root].ReplyCount++
+		if messages[root].LastReplyAt == nil || message.Timestamp.After(*messages[root].LastReplyAt) {
+			timestamp := message.Timestamp
+			messages[root].LastReplyAt = &timestamp
+		}
+	}
+	return messages
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWhatsApp(t *testing.T) {
+	export := "31.12.20, 21:41 - Messages and calls are end-to-end encrypted.\n" +
+		"31.12.20, 21:41 - Jane Doe: Happy new year\n" +
+		"see you tomorrow\n" +
+		"‎31.12.20, 21:42 - John: 12:00?\n"
+
+	chat, err := ParseWhatsApp(strings.NewReader(export), "Family", WhatsAppOptions{Location: time.UTC})
+
+	assert.NoError(t, err)
+	assert.Equal(t, SOURCE_WHATSAPP, chat.Source)
+	assert.Len(t, chat.Messages, 2)
+	assert.Equal(t, "Jane Doe", chat.Messages[0].Author)
+	assert.Equal(t, "Happy new year\nsee you tomorrow", chat.Messages[0].Content)
+	assert.Equal(t, time.Date(2020, 12, 31, 21, 41, 0, 0, time.UTC), chat.Messages[0].Timestamp)
+	assert.Equal(t, "12:00?", chat.Messages[1].Content)
+}
+
+func TestParseWhatsApp_MonthFirst(t *testing.T) {
+	export := "[1/2/21, 9:05:30 AM] Jane: Morning\n" +
+		"[1/13/21, 12:10:00 PM] John: Noon\n"
+
+	chat, err := ParseWhatsApp(strings.NewReader(export), "Friends", WhatsAppOptions{Location: time.UTC})
+
+	assert.NoError(t, err)
+	assert.Len(t, chat.Messages, 2)
+	assert.Equal(t, time.Date(2021, 1, 2, 9, 5, 30, 0, time.UTC), chat.Messages[0].Timestamp)
+	assert.Equal(t, time.Date(2021, 1, 13, 12, 10, 0, 0, time.UTC), chat.Messages[1].Timestamp)
+}
+
+func TestParseSlack(t *testing.T) {
+	archive := zipArchive(t, map[string]string{
+		"users.json":    `[{"id":"U1","name":"jane","real_name":"Jane Doe","profile":{"email":"jane@example.com"}},{"id":"U2","name":"john"}]`,
+		"channels.json": `[{"id":"C1","name":"general"},{"id":"C2","name":"random"}]`,
+		"general/2021-01-02.json": `[
+			{"type":"message","user":"U2","text":"Thanks <@U1>, see <https://commz.de|Commz>","ts":"1609592400.000200","thread_ts":"1609506000.000100"}
+		]`,
+		"general/2021-01-01.json": `[
+			{"type":"message","subtype":"channel_join","user":"U1","text":"<@U1> has joined the channel","ts":"1609505000.000100"},
+			{"type":"message","user":"U1","text":"Hello &amp; welcome","ts":"1609506000.000100","thread_ts":"1609506000.000100"}
+		]`,
+		"random/2021-01-01.json": `[{"type":"message","user":"U1","text":"Hi","ts":"1609506000.000300"}]`,
+	})
+
+	chats, err := ParseSlack(bytes.NewReader(archive), int64(len(archive)), []string{"general"})
+
+	assert.NoError(t, err)
+	assert.Len(t, chats, 1)
+
+	chat := chats[0]
+	assert.Equal(t, "#general", chat.Name)
+	assert.Equal(t, "C1", chat.Key)
+	assert.Len(t, chat.Messages, 2)
+
+	assert.Equal(t, "Jane Doe", chat.Messages[0].Author)
+	assert.Equal(t, "U1", chat.Messages[0].AuthorID)
+	assert.Equal(t, "jane@example.com", chat.Messages[0].Email)
+	assert.Equal(t, "Hello & welcome", chat.Messages[0].Content)
+	assert.Equal(t, time.Unix(1609506000, 100000), chat.Messages[0].Timestamp)
+	assert.Empty(t, chat.Messages[0].ReplyTo)
+
+	assert.Equal(t, "john", chat.Messages[1].Author)
+	assert.Equal(t, "Thanks @Jane Doe, see Commz (https://commz.de)", chat.Messages[1].Content)
+	assert.Equal(t, "1609506000.000100", chat.Messages[1].ReplyTo)
+}
+
+func TestToMessages_StableIds(t *testing.T) {
+	chatId := uuid.New()
+	jane := utils.User{ID: uuid.New()}
+	timestamp := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	imported := []ImportedMessage{
+		{Author: "Jane", Timestamp: timestamp, Content: "ok"},
+		{Author: "Jane", Timestamp: timestamp, Content: "ok"},
+		{Author: "John", Timestamp: timestamp, Content: "hi"},
+	}
+
+	first := toMessages(chatId, imported, map[string]utils.User{"Jane": jane})
+	second := toMessages(chatId, imported, map[string]utils.User{"Jane": jane})
+
+	// the same export has to result in the same ids, so a second import skips all messages
+	for i := range first {
+		assert.Equal(t, first[i].ID, second[i].ID)
+	}
+	assert.NotEqual(t, first[0].ID, first[1].ID)
+
+	assert.Equal(t, jane.ID, first[0].SenderID)
+	assert.Equal(t, timestamp, first[0].Timestamp)
+	assert.Equal(t, uuid.MustParse(utils.AIChat), first[2].SenderID)
+	assert.Equal(t, "**John:** hi", first[2].Content)
+}
+
+func TestToMessages_ReplyCounts(t *testing.T) {
+	timestamp := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	imported := []ImportedMessage{
+		{Key: "1", Author: "Jane", Timestamp: timestamp, Content: "question"},
+		{Key: "2", Author: "John", Timestamp: timestamp.Add(time.Minute), Content: "answer", ReplyTo: "1"},
+		{Key: "3", Author: "Jane", Timestamp: timestamp.Add(2 * time.Minute), Content: "thanks", ReplyTo: "1"},
+		{Key: "4", Author: "John", Timestamp: timestamp.Add(3 * time.Minute), Content: "lost", ReplyTo: "missing"},
+	}
+
+	messages := toMessages(uuid.New(), imported, map[string]utils.User{})
+
+	assert.Equal(t, 2, messages[0].ReplyCount)
+	assert.Equal(t, timestamp.Add(2*time.Minute), *messages[0].LastReplyAt)
+	for _, reply := range messages[1:] {
+		assert.Zero(t, reply.ReplyCount)
+		assert.Nil(t, reply.LastReplyAt)
+	}
+}
+
+func TestToMessages_AuthorsWithTheSameName(t *testing.T) {
+	jane := utils.User{ID: uuid.New()}
+	timestamp := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	imported := []ImportedMessage{
+		{Key: "1", Author: "Jane", AuthorID: "U1", Timestamp: timestamp, Content: "hi"},
+		{Key: "2", Author: "Jane", AuthorID: "U2", Timestamp: timestamp, Content: "hello"},
+	}
+
+	messages := toMessages(uuid.New(), imported, map[string]utils.User{"U1": jane})
+
+	// only the author with the mapped id is the user, the other one keeps the name
+	assert.Equal(t, jane.ID, messages[0].SenderID)
+	assert.Equal(t, "hi", messages[0].Content)
+	assert.Equal(t, uuid.MustParse(utils.AIChat), messages[1].SenderID)
+	assert.Equal(t, "**Jane:** hello", messages[1].Content)
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	buffer := bytes.Buffer{}
+	writer := zip.NewWriter(&buffer)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		assert.NoError(t, err)
+		_, err = file.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
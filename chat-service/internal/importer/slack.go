@@ -0,0 +1,268 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackMarkup matches mentions, channel references and links, e.g. "<@U123>" or "<https://commz.de|Commz>"
+var slackMarkup = regexp.MustCompile(`<([@#!]?)([^>|]+)(?:\|([^>]*))?>`)
+
+// notices of Slack itself that are not part of the conversation
+var slackNotices = []string{"channel_join", "channel_leave", "channel_topic", "channel_purpose", "channel_name", "group_join", "group_leave", "group_topic", "group_purpose", "group_name"}
+
+type slackUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RealName string `json:"real_name"`
+	Profile  struct {
+		Email       string `json:"email"`
+		RealName    string `json:"real_name"`
+		DisplayName string `json:"display_name"`
+	} `json:"profile"`
+}
+
+type slackChannel struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+type slackMessage struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	User     string `json:"user"`
+	Username string `json:"username"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+	Files    []struct {
+		Name  string `json:"name"`
+		Title string `json:"title"`
+	} `json:"files"`
+}
+
+// ParseSlack reads a Slack export archive. Every channel, private channel and conversation becomes a chat,
+// if channels are given only those are read
+func ParseSlack(r io.ReaderAt, size int64, channels []string) ([]ImportedChat, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	users := []slackUser{}
+	err = readSlackFile(files, "users.json", &users)
+	if err != nil {
+		return nil, err
+	}
+
+	userMap := make(map[string]slackUser, len(users))
+	for _, user := range users {
+		userMap[user.ID] = user
+	}
+
+	chats := []ImportedChat{}
+	// channels and private channels are stored in a folder named like the channel, conversations in a folder named by id
+	for _, list := range []struct {
+		file   string
+		byName bool
+	}{{"channels.json", true}, {"groups.json", true}, {"mpims.json", true}, {"dms.json", false}} {
+		conversations := []slackChannel{}
+		err = readSlackFile(files, list.file, &conversations)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, conversation := range conversations {
+			folder, name := conversation.ID, directMessageName(conversation, userMap)
+			if list.byName {
+				folder, name = conversation.Name, "#"+conversation.Name
+			}
+
+			if len(channels) > 0 && !slices.Contains(channels, conversation.Name) && !slices.Contains(channels, conversation.ID) {
+				continue
+			}
+
+			chat, err := parseSlackChannel(files, folder, conversation.ID, name, userMap)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(chat.Messages) > 0 {
+				chats = append(chats, *chat)
+			}
+		}
+	}
+
+	return chats, nil
+}
+
+// readSlackFile decodes a json file of the archive, missing files are skipped
+func readSlackFile(files map[string]*zip.File, name string, value any) error {
+	file, ok := files[name]
+	if !ok {
+		return nil
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	err = json.NewDecoder(reader).Decode(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return nil
+}
+
+func parseSlackChannel(files map[string]*zip.File, folder string, id string, name string, users map[string]slackUser) (*ImportedChat, error) {
+	// every day of a channel is stored in its own file, e.g. general/2020-12-31.json
+	days := []string{}
+	for file := range files {
+		if path.Dir(file) == folder && path.Ext(file) == ".json" {
+			days = append(days, file)
+		}
+	}
+	slices.Sort(days)
+
+	messages := []slackMessage{}
+	for _, day := range days {
+		dayMessages := []slackMessage{}
+		err := readSlackFile(files, day, &dayMessages)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, dayMessages...)
+	}
+
+	slices.SortStableFunc(messages, func(a, b slackMessage) int { return strings.Compare(a.TS, b.TS) })
+
+	chat := ImportedChat{
+		Source:   SOURCE_SLACK,
+		Key:      id,
+		Name:     name,
+		Messages: make([]ImportedMessage, 0, len(messages)),
+	}
+
+	for _, message := range messages {
+		if message.Type != "message" || slices.Contains(slackNotices, message.Subtype) {
+			continue
+		}
+
+		timestamp, err := parseSlackTimestamp(message.TS)
+		if err != nil {
+			return nil, err
+		}
+
+		user := users[message.User]
+		imported := ImportedMessage{
+			Key:       message.TS,
+			Author:    slackName(user, message),
+			AuthorID:  message.User,
+			Email:     user.Profile.Email,
+			Timestamp: timestamp,
+			Content:   slackText(message, users),
+		}
+
+		// replies of a thread reference the first message of the thread
+		if message.ThreadTS != "" && message.ThreadTS != message.TS {
+			imported.ReplyTo = message.ThreadTS
+		}
+
+		chat.Messages = append(chat.Messages, imported)
+	}
+
+	return &chat, nil
+}
+
+// parseSlackTimestamp reads timestamps like "1609455600.000200", they also identify the message in its channel
+func parseSlackTimestamp(ts string) (time.Time, error) {
+	seconds, fraction, _ := strings.Cut(ts, ".")
+	sec, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", ts)
+	}
+
+	micro := int64(0)
+	if fraction != "" {
+		micro, err = strconv.ParseInt((fraction + "000000")[:6], 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", ts)
+		}
+	}
+
+	return time.Unix(sec, micro*int64(time.Microsecond)), nil
+}
+
+func slackName(user slackUser, message slackMessage) string {
+	for _, name := range []string{user.Profile.RealName, user.RealName, user.Profile.DisplayName, user.Name, message.Username, message.User} {
+		if name != "" {
+			return name
+		}
+	}
+	return "Unknown"
+}
+
+// slackText replaces the markup of mentions and links and lists the attached files
+func slackText(message slackMessage, users map[string]slackUser) string {
+	text := slackMarkup.ReplaceAllStringFunc(message.Text, func(markup string) string {
+		match := slackMarkup.FindStringSubmatch(markup)
+		kind, target, label := match[1], match[2], match[3]
+
+		switch kind {
+		case "@":
+			return "@" + slackName(users[target], slackMessage{User: target})
+		case "#":
+			if label != "" {
+				return "#" + label
+			}
+			return "#" + target
+		case "!":
+			// special mentions like <!here> or <!channel>
+			return "@" + target
+		}
+
+		if label != "" && label != target {
+			return fmt.Sprintf("%s (%s)", label, target)
+		}
+		return target
+	})
+	text = html.UnescapeString(text)
+
+	for _, file := range message.Files {
+		name := file.Name
+		if name == "" {
+			name = file.Title
+		}
+		text += fmt.Sprintf("\n📎 %s", name)
+	}
+
+	return strings.TrimSpace(text)
+}
+
+func directMessageName(conversation slackChannel, users map[string]slackUser) string {
+	names := []string{}
+	for _, member := range conversation.Members {
+		names = append(names, slackName(users[member], slackMessage{User: member}))
+	}
+	if len(names) == 0 {
+		return "Direct Messages"
+	}
+	return strings.Join(names, ", ")
+}
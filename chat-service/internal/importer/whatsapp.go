@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// whatsappLine matches the first line of a message in the formats of the different WhatsApp apps, e.g.
+// "12/31/20, 9:41 PM - Jane Doe: Hello" or "[31.12.20, 21:41:05] Jane Doe: Hello"
+var whatsappLine = regexp.MustCompile(`^\[?(\d{1,4})[./-](\d{1,2})[./-](\d{1,4}),?\s+(\d{1,2}):(\d{2})(?::(\d{2}))?\s*([AaPp]\.?\s?[Mm]\.?)?\]?\s*(?:-\s*)?(.*)$`)
+
+// WhatsAppOptions control how the timestamps of a WhatsApp export are read
+type WhatsAppOptions struct {
+	// Location is the time zone of the phone the chat was exported from
+	Location *time.Location
+	// DayFirst tells if dates are written day first, it is detected from the export if nil
+	DayFirst *bool
+}
+
+type whatsappEntry struct {
+	date    [3]int
+	hour    int
+	minute  int
+	second  int
+	author  string
+	content string
+}
+
+// ParseWhatsApp reads the .txt export of a WhatsApp chat. Notices of WhatsApp itself are left out
+func ParseWhatsApp(r io.Reader, name string, opts WhatsAppOptions) (*ImportedChat, error) {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+
+	entries := []*whatsappEntry{}
+	var current *whatsappEntry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := normalizeWhatsAppLine(scanner.Text())
+
+		match := whatsappLine.FindStringSubmatch(line)
+		if match == nil {
+			// lines without a date continue the previous message
+			if current != nil {
+				current.content += "\n" + line
+			}
+			continue
+		}
+
+		author, content, found := strings.Cut(match[8], ": ")
+		if !found {
+			// notices like "Messages and calls are end-to-end encrypted" have no author
+			current = nil
+			continue
+		}
+
+		entry, err := parseWhatsAppEntry(match)
+		if err != nil {
+			return nil, err
+		}
+		entry.author = strings.TrimSpace(author)
+		entry.content = content
+
+		entries = append(entries, entry)
+		current = entry
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	dayFirst := detectDayFirst(entries)
+	if opts.DayFirst != nil {
+		dayFirst = *opts.DayFirst
+	}
+
+	chat := ImportedChat{
+		Source:   SOURCE_WHATSAPP,
+		Key:      name,
+		Name:     name,
+		Messages: make([]ImportedMessage, 0, len(entries)),
+	}
+
+	for _, entry := range entries {
+		timestamp, err := entry.timestamp(dayFirst, opts.Location)
+		if err != nil {
+			return nil, err
+		}
+
+		chat.Messages = append(chat.Messages, ImportedMessage{
+			Author:    entry.author,
+			Timestamp: timestamp,
+			Content:   strings.TrimRight(entry.content, "\n "),
+		})
+	}
+
+	return &chat, nil
+}
+
+// normalizeWhatsAppLine removes the invisible characters newer exports put around dates and names
+func normalizeWhatsAppLine(line string) string {
+	line = strings.NewReplacer("\u202f", " ", "\u00a0", " ").Replace(line)
+	return strings.TrimLeft(line, "\u200e\u200f\ufeff")
+}
+
+func parseWhatsAppEntry(match []string) (*whatsappEntry, error) {
+	entry := whatsappEntry{}
+	for i := range entry.date {
+		entry.date[i], _ = strconv.Atoi(match[i+1])
+	}
+	entry.hour, _ = strconv.Atoi(match[4])
+	entry.minute, _ = strconv.Atoi(match[5])
+	if match[6] != "" {
+		entry.second, _ = strconv.Atoi(match[6])
+	}
+
+	meridiem := strings.ToLower(strings.NewReplacer(".", "", " ", "").Replace(match[7]))
+	switch {
+	case meridiem == "" && entry.hour > 23:
+		return nil, fmt.Errorf("invalid time %s:%s", match[4], match[5])
+	case meridiem == "":
+	case entry.hour < 1 || entry.hour > 12:
+		return nil, fmt.Errorf("invalid time %s:%s %s", match[4], match[5], match[7])
+	case meridiem == "am" && entry.hour == 12:
+		entry.hour = 0
+	case meridiem == "pm" && entry.hour != 12:
+		entry.hour += 12
+	}
+
+	return &entry, nil
+}
+
+// detectDayFirst checks if any date can only be read day first or month first,
+// exports without such a date are read day first like most WhatsApp locales write them
+func detectDayFirst(entries []*whatsappEntry) bool {
+	for _, entry := range entries {
+		if entry.date[0] > 31 {
+			continue
+		}
+		if entry.date[0] > 12 {
+			return true
+		}
+		if entry.date[1] > 12 {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *whatsappEntry) timestamp(dayFirst bool, location *time.Location) (time.Time, error) {
+	year, month, day := e.date[2], e.date[1], e.date[0]
+	switch {
+	case e.date[0] > 31:
+		// year first, e.g. 2020-12-31
+		year, month, day = e.date[0], e.date[1], e.date[2]
+	case !dayFirst:
+		month, day = e.date[0], e.date[1]
+	}
+
+	if year < 100 {
+		year += 2000
+	}
+
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, fmt.Errorf("invalid date %d.%d.%d", e.date[0], e.date[1], e.date[2])
+	}
+
+	return time.Date(year, time.Month(month), day, e.hour, e.minute, e.second, 0, location), nil
+}
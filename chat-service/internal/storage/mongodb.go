@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"slices"
 	"time"
 
@@ -14,12 +15,23 @@ import (
 
 const (
 	DB_NAME = "commz"
+	// events are only relevant for connected clients and can be removed soon
+	EVENT_TTL = 1 * time.Hour
 )
 
 type MongoDBStorage struct {
 	// MongoDB client
-	chatsCollection    *mongo.Collection
-	messagesCollection *mongo.Collection
+	chatsCollection     *mongo.Collection
+	messagesCollection  *mongo.Collection
+	eventsCollection    *mongo.Collection
+	revisionsCollection *mongo.Collection
+	scheduledCollection *mongo.Collection
+	invitesCollection   *mongo.Collection
+	settingsCollection  *mongo.Collection
+	gamesCollection     *mongo.Collection
+	scoresCollection    *mongo.Collection
+	quizzesCollection   *mongo.Collection
+	votesCollection     *mongo.Collection
 }
 
 func NewMongoDBStorage(connectionURI string) (*MongoDBStorage, error) {
@@ -29,19 +41,133 @@ func NewMongoDBStorage(connectionURI string) (*MongoDBStorage, error) {
 	}
 	chats := client.Database(DB_NAME).Collection("chats")
 	messages := client.Database(DB_NAME).Collection("messages")
+	events := client.Database(DB_NAME).Collection("events")
+	revisions := client.Database(DB_NAME).Collection("revisions")
+	scheduled := client.Database(DB_NAME).Collection("scheduled_messages")
+	invites := client.Database(DB_NAME).Collection("invites")
+	settings := client.Database(DB_NAME).Collection("chat_settings")
+	games := client.Database(DB_NAME).Collection("games")
+	scores := client.Database(DB_NAME).Collection("game_scores")
+	quizzes := client.Database(DB_NAME).Collection("quizzes")
+	votes := client.Database(DB_NAME).Collection("poll_votes")
 
 	// ensure indexes
 	_, err = messages.Indexes().CreateOne(context.Background(), mongo.IndexModel{
 		Keys: bson.M{"chat_id": 1},
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = messages.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "chat_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.M{"thread_id": 1}},
+		{Keys: bson.M{"reply_to": 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = messages.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.M{"content": "text"},
+		// chats mix languages, so words are matched without stemming and stop words
+		Options: options.Index().SetDefaultLanguage("none"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = events.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.M{"timestamp": 1},
+		Options: options.Index().SetExpireAfterSeconds(int32(EVENT_TTL.Seconds())),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = revisions.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.M{"message_id": 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = scheduled.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "send_at", Value: 1}}},
+		{Keys: bson.D{{Key: "sender_id", Value: 1}, {Key: "send_at", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = invites.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.M{"chat_id": 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = settings.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "chat_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = games.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		// only one game per chat can be active, even with several replicas
+		{
+			Keys:    bson.M{"chat_id": 1},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"active": true}),
+		},
+		{Keys: bson.D{{Key: "active", Value: 1}, {Key: "deadline", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = scores.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "chat_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = quizzes.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		// only one quiz per chat can be active, even with several replicas
+		{
+			Keys:    bson.M{"chat_id": 1},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"active": true}),
+		},
+		{Keys: bson.D{{Key: "active", Value: 1}, {Key: "deadline", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	_, err = votes.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		// every user can only vote once per poll
+		Keys:    bson.D{{Key: "message_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &MongoDBStorage{
-		chatsCollection:    chats,
-		messagesCollection: messages,
+		chatsCollection:     chats,
+		messagesCollection:  messages,
+		eventsCollection:    events,
+		revisionsCollection: revisions,
+		scheduledCollection: scheduled,
+		invitesCollection:   invites,
+		settingsCollection:  settings,
+		gamesCollection:     games,
+		scoresCollection:    scores,
+		quizzesCollection:   quizzes,
+		votesCollection:     votes,
 	}, nil
 }
 
@@ -61,7 +187,7 @@ func (m *MongoDBStorage) GetChat(id uuid.UUID) (*utils.Chat, error) {
 	return &chat, nil
 }
 
-func (m *MongoDBStorage) GetChats(user uuid.UUID) ([]utils.Chat, error) {
+func (m *MongoDBStorage) GetChats(user uuid.UUID, opts utils.ChatListOptions) ([]utils.Chat, error) {
 
 	filter := bson.M{"members": user}
 
@@ -78,6 +204,15 @@ func (m *MongoDBStorage) GetChats(user uuid.UUID) ([]utils.Chat, error) {
 		return nil, err
 	}
 
+	err = m.addChatSummaries(ctx, user, chats)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.IncludeMessages {
+		return chats, nil
+	}
+
 	// fetch the last 10 messages that were sent in this chat
 	for i, chat := range chats {
 		filter := bson.M{"chat_id": chat.ID}
@@ -106,6 +241,83 @@ func (m *MongoDBStorage) GetChats(user uuid.UUID) ([]utils.Chat, error) {
 	return chats, nil
 }
 
+type chatSummary struct {
+	ChatID      uuid.UUID     `bson:"_id"`
+	LastMessage utils.Message `bson:"last_message"`
+	LastReadAt  *time.Time    `bson:"last_read_at"`
+	UnreadCount int           `bson:"unread_count"`
+}
+
+// addChatSummaries sets the unread count, the last message and the last read time
+// of the user for every chat with a single aggregation over all messages of the chats
+func (m *MongoDBStorage) addChatSummaries(ctx context.Context, user uuid.UUID, chats []utils.Chat) error {
+	if len(chats) == 0 {
+		return nil
+	}
+
+	chatIds := make([]uuid.UUID, 0, len(chats))
+	for _, chat := range chats {
+		chatIds = append(chatIds, chat.ID)
+	}
+
+	readBy := "$read_by." + user.String()
+	unread := bson.M{"$and": bson.A{
+		bson.M{"$ne": bson.A{"$sender", user}},
+		bson.M{"$ne": bson.A{"$deleted", true}},
+		bson.M{"$eq": bson.A{bson.M{"$type": readBy}, "missing"}},
+		// messages from before read receipts only have the shared read flag
+		bson.M{"$not": bson.A{bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{"$read", true}},
+			bson.M{"$eq": bson.A{bson.M{"$type": "$read_by"}, "missing"}},
+		}}}},
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"chat_id": bson.M{"$in": chatIds}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "chat_id", Value: 1}, {Key: "timestamp", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$chat_id",
+			"last_message": bson.M{"$first": "$$ROOT"},
+			"last_read_at": bson.M{"$max": readBy},
+			"unread_count": bson.M{"$sum": bson.M{"$cond": bson.A{unread, 1, 0}}},
+		}}},
+	}
+
+	result, err := m.messagesCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	summaries := []chatSummary{}
+	err = result.All(ctx, &summaries)
+	if err != nil {
+		return err
+	}
+
+	summaryMap := make(map[uuid.UUID]chatSummary, len(summaries))
+	for _, summary := range summaries {
+		summaryMap[summary.ChatID] = summary
+	}
+
+	for i := range chats {
+		summary, ok := summaryMap[chats[i].ID]
+		if !ok {
+			continue
+		}
+
+		// remove content of deleted messages
+		if summary.LastMessage.Deleted {
+			summary.LastMessage.Content = ""
+		}
+
+		chats[i].UnreadCount = summary.UnreadCount
+		chats[i].LastMessage = &summary.LastMessage
+		chats[i].LastReadAt = summary.LastReadAt
+	}
+
+	return nil
+}
+
 func (m *MongoDBStorage) GetChatMessages(chatId uuid.UUID, limit, offset int) ([]utils.Message, error) {
 	filter := bson.M{"chat_id": chatId}
 	opts := options.Find().
@@ -129,6 +341,51 @@ func (m *MongoDBStorage) GetChatMessages(chatId uuid.UUID, limit, offset int) ([
 	return messages, nil
 }
 
+func (m *MongoDBStorage) GetChatMessagesFrom(chatId uuid.UUID, cursor utils.MessageCursor, older bool, inclusive bool, limit int) ([]utils.Message, error) {
+	timeOperator, idOperator, order := "$gt", "$gt", 1
+	if older {
+		timeOperator, idOperator, order = "$lt", "$lt", -1
+	}
+	if inclusive {
+		idOperator += "e"
+	}
+
+	filter := bson.M{
+		"chat_id": chatId,
+		"$or": bson.A{
+			bson.M{"timestamp": bson.M{timeOperator: cursor.Timestamp}},
+			bson.M{"timestamp": cursor.Timestamp, "_id": bson.M{idOperator: cursor.ID}},
+		},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: order}, {Key: "_id", Value: order}}).
+		SetLimit(int64(limit))
+
+	ctx := context.Background()
+	result, err := m.messagesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []utils.Message{}
+	err = result.All(ctx, &messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove content of deleted messages
+	for i := range messages {
+		if messages[i].Deleted {
+			messages[i].Content = ""
+		}
+	}
+
+	if older {
+		slices.Reverse(messages)
+	}
+	return messages, nil
+}
+
 func (m *MongoDBStorage) MemberOfChat(userId uuid.UUID, chatId uuid.UUID) error {
 	filter := bson.M{"_id": chatId, "members": userId}
 	ctx := context.Background()
@@ -160,19 +417,67 @@ func (m *MongoDBStorage) DeleteChat(chat uuid.UUID) error {
 }
 
 func (m *MongoDBStorage) DeleteMessage(message uuid.UUID) error {
-	ctx := context.Background()
-	filter := bson.M{"_id": message}
-	_, err := m.messagesCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"deleted": true, "updatedAt": time.Now()}})
+	_, err := m.softDeleteMessages([]uuid.UUID{message})
 	return err
 }
 
+// softDeleteMessages marks messages as deleted, the updated timestamp lets the gateway push the deletion
+func (m *MongoDBStorage) softDeleteMessages(ids []uuid.UUID) (int64, error) {
+	ctx := context.Background()
+
+	// the votes of deleted polls are not needed anymore
+	_, err := m.votesCollection.DeleteMany(ctx, bson.M{"message_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+
+	// previews and polls would keep the removed content readable
+	result, err := m.messagesCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{
+		"$set":   bson.M{"deleted": true, "updatedAt": time.Now()},
+		"$unset": bson.M{"previews": "", "poll": ""},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// UpdateMessage stores the edited fields of a message, concurrent changes like reactions,
+// read receipts and replies are kept
 func (m *MongoDBStorage) UpdateMessage(message utils.Message) error {
 	ctx := context.Background()
 	filter := bson.M{"_id": message.ID}
-	_, err := m.messagesCollection.UpdateOne(ctx, filter, bson.M{"$set": message}, options.Update().SetUpsert(true))
+	_, err := m.messagesCollection.UpdateOne(ctx, filter, messageEdit(message))
 	return err
 }
 
+// messageEdit is the update pipeline of an edited message. The values are literals, so content
+// starting with a $ is not read as a field path. The previews are removed if the content changed
+func messageEdit(message utils.Message) bson.A {
+	optional := func(value any, set bool) any {
+		if !set {
+			return "$$REMOVE"
+		}
+		return bson.M{"$literal": value}
+	}
+
+	return bson.A{bson.M{"$set": bson.M{
+		"content":    bson.M{"$literal": message.Content},
+		"media":      bson.M{"$literal": message.Media},
+		"edited":     message.Edited,
+		"edit_count": message.EditCount,
+		"edited_at":  optional(message.EditedAt, message.EditedAt != nil),
+		"updatedAt":  message.UpdatedAt,
+		"kind":       bson.M{"$literal": message.Kind},
+		"payload":    optional(message.Payload, message.Payload != nil),
+		"previews": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$content", bson.M{"$literal": message.Content}}},
+			"$previews",
+			"$$REMOVE",
+		}},
+	}}}
+}
+
 func (m *MongoDBStorage) UpdateChatActivity(chat uuid.UUID) error {
 	ctx := context.Background()
 	filter := bson.M{"_id": chat}
@@ -197,3 +502,991 @@ func (m *MongoDBStorage) GetMessage(messageId uuid.UUID) (utils.Message, error)
 	}
 	return message, nil
 }
+
+func (m *MongoDBStorage) AddReaction(messageId uuid.UUID, reaction utils.Reaction) error {
+	ctx := context.Background()
+	// only push the reaction if the user has not reacted with that emoji yet
+	filter := bson.M{
+		"_id": messageId,
+		"reactions": bson.M{"$not": bson.M{"$elemMatch": bson.M{
+			"emoji": reaction.Emoji,
+			"user":  reaction.UserID,
+		}}},
+	}
+	_, err := m.messagesCollection.UpdateOne(ctx, filter, bson.M{
+		"$push": bson.M{"reactions": reaction},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	})
+	return err
+}
+
+func (m *MongoDBStorage) RemoveReaction(messageId uuid.UUID, userId uuid.UUID, emoji string) error {
+	ctx := context.Background()
+	filter := bson.M{"_id": messageId, "reactions": bson.M{"$elemMatch": bson.M{"emoji": emoji, "user": userId}}}
+	_, err := m.messagesCollection.UpdateOne(ctx, filter, bson.M{
+		"$pull": bson.M{"reactions": bson.M{"emoji": emoji, "user": userId}},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	})
+	return err
+}
+
+func (m *MongoDBStorage) GetThread(rootId uuid.UUID) ([]utils.Message, error) {
+	// replies sent before threads were introduced only reference their parent
+	filter := bson.M{"$or": bson.A{
+		bson.M{"thread_id": rootId},
+		bson.M{"reply_to": rootId},
+	}}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+
+	ctx := context.Background()
+	result, err := m.messagesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []utils.Message{}
+	err = result.All(ctx, &messages)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove content of deleted messages
+	for i := range messages {
+		if messages[i].Deleted {
+			messages[i].Content = ""
+		}
+	}
+
+	return messages, nil
+}
+
+func (m *MongoDBStorage) AddThreadReply(rootId uuid.UUID, timestamp time.Time) error {
+	ctx := context.Background()
+	filter := bson.M{"_id": rootId}
+	_, err := m.messagesCollection.UpdateOne(ctx, filter, bson.M{
+		"$inc": bson.M{"reply_count": 1},
+		"$max": bson.M{"last_reply_at": timestamp},
+		"$set": bson.M{"updatedAt": time.Now()},
+	})
+	return err
+}
+
+func (m *MongoDBStorage) MarkMessageRead(messageId uuid.UUID, userId uuid.UUID, readAt time.Time) error {
+	ctx := context.Background()
+	readBy := "read_by." + userId.String()
+	// keep the time the user has read the message first
+	filter := bson.M{"_id": messageId, readBy: bson.M{"$exists": false}}
+	_, err := m.messagesCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		readBy:      readAt,
+		"read":      true,
+		"updatedAt": time.Now(),
+	}})
+	return err
+}
+
+func (m *MongoDBStorage) MarkChatRead(chatId uuid.UUID, userId uuid.UUID, upTo time.Time, readAt time.Time) (int64, error) {
+	ctx := context.Background()
+	readBy := "read_by." + userId.String()
+	filter := bson.M{
+		"chat_id":   chatId,
+		"timestamp": bson.M{"$lte": upTo},
+		"sender":    bson.M{"$ne": userId},
+		readBy:      bson.M{"$exists": false},
+	}
+	// updatedAt is not touched on purpose, the change is announced by a single event
+	// instead of pushing every message to the clients again
+	result, err := m.messagesCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{
+		readBy: readAt,
+		"read": true,
+	}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (m *MongoDBStorage) SaveEvent(event utils.Event) error {
+	ctx := context.Background()
+	_, err := m.eventsCollection.InsertOne(ctx, event)
+	return err
+}
+
+func (m *MongoDBStorage) GetChatIDs(user uuid.UUID) ([]uuid.UUID, error) {
+	ctx := context.Background()
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	result, err := m.chatsCollection.Find(ctx, bson.M{"members": user}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	chats := []utils.Chat{}
+	err = result.All(ctx, &chats)
+	if err != nil {
+		return nil, err
+	}
+
+	chatIds := make([]uuid.UUID, 0, len(chats))
+	for _, chat := range chats {
+		chatIds = append(chatIds, chat.ID)
+	}
+	return chatIds, nil
+}
+
+type searchHit struct {
+	Message utils.Message
+	Score   float64 `bson:"score"`
+}
+
+// UnmarshalBSON reads the message and the text score of the same document, the embedded message
+// would otherwise decode the whole document with its own UnmarshalBSON and skip the score
+func (h *searchHit) UnmarshalBSON(data []byte) error {
+	err := bson.Unmarshal(data, &h.Message)
+	if err != nil {
+		return err
+	}
+
+	score := struct {
+		Score float64 `bson:"score"`
+	}{}
+	err = bson.Unmarshal(data, &score)
+	if err != nil {
+		return err
+	}
+	h.Score = score.Score
+	return nil
+}
+
+func (m *MongoDBStorage) SearchMessages(query utils.SearchQuery) ([]utils.SearchResult, int64, error) {
+	filter := bson.M{
+		"$text":   bson.M{"$search": query.Query},
+		"chat_id": bson.M{"$in": query.ChatIDs},
+		"deleted": bson.M{"$ne": true},
+	}
+	if query.SenderID != nil {
+		filter["sender"] = *query.SenderID
+	}
+	if query.From != nil || query.To != nil {
+		timestamp := bson.M{}
+		if query.From != nil {
+			timestamp["$gte"] = *query.From
+		}
+		if query.To != nil {
+			timestamp["$lte"] = *query.To
+		}
+		filter["timestamp"] = timestamp
+	}
+	if query.HasMedia {
+		filter["media.0"] = bson.M{"$exists": true}
+	}
+	if query.RepliesOnly {
+		filter["reply_to"] = bson.M{"$ne": nil}
+	}
+
+	ctx := context.Background()
+	total, err := m.messagesCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "timestamp", Value: -1}}).
+		SetLimit(int64(query.Limit)).
+		SetSkip(int64(query.Offset))
+
+	result, err := m.messagesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := []searchHit{}
+	err = result.All(ctx, &hits)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]utils.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, utils.SearchResult{
+			Message: hit.Message,
+			Score:   hit.Score,
+		})
+	}
+
+	return results, total, nil
+}
+
+func (m *MongoDBStorage) SaveRevision(revision utils.Revision) error {
+	ctx := context.Background()
+	_, err := m.revisionsCollection.InsertOne(ctx, revision)
+	return err
+}
+
+func (m *MongoDBStorage) GetRevisions(messageId uuid.UUID) ([]utils.Revision, error) {
+	filter := bson.M{"message_id": messageId}
+	opts := options.Find().SetSort(bson.D{{Key: "edited_at", Value: 1}})
+
+	ctx := context.Background()
+	result, err := m.revisionsCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []utils.Revision{}
+	err = result.All(ctx, &revisions)
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (m *MongoDBStorage) SetChatRole(chatId uuid.UUID, userId uuid.UUID, role utils.ChatRole) error {
+	ctx := context.Background()
+	filter := bson.M{"_id": chatId, "members": userId}
+	_, err := m.chatsCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"roles." + userId.String(): role}})
+	return err
+}
+
+// AddChatMembers adds the users as plain members, their role is stored explicitly
+// so the creator fallback of chats without roles does not apply to them
+func (m *MongoDBStorage) AddChatMembers(chatId uuid.UUID, members []uuid.UUID) error {
+	ctx := context.Background()
+	filter := bson.M{"_id": chatId}
+
+	set := bson.M{"last_active": time.Now()}
+	for _, member := range members {
+		set["roles."+member.String()] = utils.ROLE_MEMBER
+	}
+
+	_, err := m.chatsCollection.UpdateOne(ctx, filter, bson.M{
+		"$addToSet": bson.M{"members": bson.M{"$each": members}},
+		"$set":      set,
+	})
+	return err
+}
+
+func (m *MongoDBStorage) RemoveChatMember(chatId uuid.UUID, userId uuid.UUID) error {
+	ctx := context.Background()
+	filter := bson.M{"_id": chatId}
+	_, err := m.chatsCollection.UpdateOne(ctx, filter, bson.M{
+		"$pull":  bson.M{"members": userId},
+		"$unset": bson.M{"roles." + userId.String(): ""},
+		"$set":   bson.M{"last_active": time.Now()},
+	})
+	return err
+}
+
+func (m *MongoDBStorage) AddPin(chatId uuid.UUID, pin utils.Pin, maxPins int) (bool, error) {
+	ctx := context.Background()
+	// only pin if the message is not pinned yet and the chat has room for another pin
+	filter := bson.M{
+		"_id":                             chatId,
+		"pins.message_id":                 bson.M{"$ne": pin.MessageID},
+		fmt.Sprintf("pins.%d", maxPins-1): bson.M{"$exists": false},
+	}
+	result, err := m.chatsCollection.UpdateOne(ctx, filter, bson.M{"$push": bson.M{"pins": pin}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (m *MongoDBStorage) RemovePin(chatId uuid.UUID, messageId uuid.UUID) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{"_id": chatId}
+	result, err := m.chatsCollection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"pins": bson.M{"message_id": messageId}}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (m *MongoDBStorage) SaveScheduledMessage(message utils.ScheduledMessage) error {
+	ctx := context.Background()
+	_, err := m.scheduledCollection.InsertOne(ctx, message)
+	return err
+}
+
+func (m *MongoDBStorage) GetScheduledMessage(id uuid.UUID) (*utils.ScheduledMessage, error) {
+	ctx := context.Background()
+	message := utils.ScheduledMessage{}
+	err := m.scheduledCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&message)
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (m *MongoDBStorage) GetScheduledMessages(userId uuid.UUID) ([]utils.ScheduledMessage, error) {
+	filter := bson.M{"sender_id": userId}
+	opts := options.Find().SetSort(bson.D{{Key: "send_at", Value: 1}})
+
+	ctx := context.Background()
+	result, err := m.scheduledCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []utils.ScheduledMessage{}
+	err = result.All(ctx, &messages)
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (m *MongoDBStorage) UpdateScheduledMessage(message utils.ScheduledMessage) (bool, error) {
+	ctx := context.Background()
+	// messages that are being sent right now can not be changed anymore
+	filter := bson.M{
+		"_id":       message.ID,
+		"sender_id": message.SenderID,
+		"status":    bson.M{"$in": []utils.ScheduledStatus{utils.SCHEDULED_PENDING, utils.SCHEDULED_FAILED}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"content":    message.Content,
+			"media":      message.Media,
+			"send_at":    message.SendAt,
+			"status":     message.Status,
+			"updated_at": message.UpdatedAt,
+		},
+		"$unset": bson.M{"error": ""},
+	}
+	result, err := m.scheduledCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (m *MongoDBStorage) DeleteScheduledMessage(id uuid.UUID, userId uuid.UUID) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{
+		"_id":       id,
+		"sender_id": userId,
+		"status":    bson.M{"$ne": utils.SCHEDULED_SENDING},
+	}
+	result, err := m.scheduledCollection.DeleteOne(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// ClaimScheduledMessage atomically locks the next due message, so only one replica delivers it.
+// Messages whose lock expired are claimed again, in case a replica stopped while sending them
+func (m *MongoDBStorage) ClaimScheduledMessage(now time.Time, lease time.Duration) (*utils.ScheduledMessage, error) {
+	ctx := context.Background()
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": utils.SCHEDULED_PENDING, "send_at": bson.M{"$lte": now}},
+			{"status": utils.SCHEDULED_SENDING, "locked_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"status": utils.SCHEDULED_SENDING, "locked_until": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "send_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	message := utils.ScheduledMessage{}
+	err := m.scheduledCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// FinishScheduledMessage removes a delivered message or marks it as failed if there is a failure
+func (m *MongoDBStorage) FinishScheduledMessage(id uuid.UUID, failure string) error {
+	ctx := context.Background()
+	filter := bson.M{"_id": id}
+	if failure == "" {
+		_, err := m.scheduledCollection.DeleteOne(ctx, filter)
+		return err
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"status": utils.SCHEDULED_FAILED, "error": failure, "updated_at": time.Now()},
+		"$unset": bson.M{"locked_until": ""},
+	}
+	_, err := m.scheduledCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (m *MongoDBStorage) SetChatRetention(chatId uuid.UUID, retention *utils.Retention) error {
+	ctx := context.Background()
+	filter := bson.M{"_id": chatId}
+	update := bson.M{"$set": bson.M{"retention": retention}}
+	if retention == nil {
+		update = bson.M{"$unset": bson.M{"retention": ""}}
+	}
+	_, err := m.chatsCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (m *MongoDBStorage) GetChatsWithRetention() ([]utils.Chat, error) {
+	filter := bson.M{"retention": bson.M{"$exists": true}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "retention": 1})
+
+	ctx := context.Background()
+	result, err := m.chatsCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	chats := []utils.Chat{}
+	err = result.All(ctx, &chats)
+	if err != nil {
+		return nil, err
+	}
+	return chats, nil
+}
+
+// ExpireMessages deletes the content of all messages of the chat sent before the given time,
+// including previous versions of edited messages. It returns the number of deleted messages
+func (m *MongoDBStorage) ExpireMessages(chatId uuid.UUID, before time.Time) (int64, error) {
+	ctx := context.Background()
+	filter := bson.M{"chat_id": chatId, "timestamp": bson.M{"$lt": before}, "deleted": bson.M{"$ne": true}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+
+	result, err := m.messagesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	var expired []struct {
+		ID uuid.UUID `bson:"_id"`
+	}
+	err = result.All(ctx, &expired)
+	if err != nil || len(expired) == 0 {
+		return 0, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(expired))
+	for _, message := range expired {
+		ids = append(ids, message.ID)
+	}
+
+	_, err = m.revisionsCollection.DeleteMany(ctx, bson.M{"message_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+
+	// expired messages must not keep anything their senders wrote, attached or reacted
+	_, err = m.messagesCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{
+		"$set": bson.M{"content": "", "media": []uuid.UUID{}, "command": ""},
+		"$unset": bson.M{
+			"previews":       "",
+			"poll":           "",
+			"forwarded_from": "",
+			"payload":        "",
+			"reactions":      "",
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return m.softDeleteMessages(ids)
+}
+
+func (m *MongoDBStorage) SaveInvite(invite utils.Invite) error {
+	ctx := context.Background()
+	_, err := m.invitesCollection.InsertOne(ctx, invite)
+	return err
+}
+
+func (m *MongoDBStorage) GetInvite(id uuid.UUID) (*utils.Invite, error) {
+	ctx := context.Background()
+	invite := utils.Invite{}
+	err := m.invitesCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&invite)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (m *MongoDBStorage) GetInvites(chatId uuid.UUID) ([]utils.Invite, error) {
+	filter := bson.M{"chat_id": chatId, "revoked": false}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	ctx := context.Background()
+	result, err := m.invitesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	invites := []utils.Invite{}
+	err = result.All(ctx, &invites)
+	if err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (m *MongoDBStorage) RevokeInvite(chatId uuid.UUID, inviteId uuid.UUID) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{"_id": inviteId, "chat_id": chatId, "revoked": false}
+	result, err := m.invitesCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// UseInvite counts a use of the invite if it is still active, so the usage limit holds with concurrent joins
+func (m *MongoDBStorage) UseInvite(inviteId uuid.UUID, now time.Time) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{
+		"_id":     inviteId,
+		"revoked": false,
+		"$and": []bson.M{
+			{"$or": []bson.M{{"expires_at": nil}, {"expires_at": bson.M{"$gt": now}}}},
+			{"$or": []bson.M{{"max_uses": 0}, {"$expr": bson.M{"$lt": []string{"$uses", "$max_uses"}}}}},
+		},
+	}
+	result, err := m.invitesCollection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"uses": 1}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (m *MongoDBStorage) GetChatSettings(userId uuid.UUID) ([]utils.ChatSettings, error) {
+	ctx := context.Background()
+	result, err := m.settingsCollection.Find(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return nil, err
+	}
+
+	settings := []utils.ChatSettings{}
+	err = result.All(ctx, &settings)
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (m *MongoDBStorage) SaveChatSettings(settings utils.ChatSettings) error {
+	ctx := context.Background()
+	filter := bson.M{"user_id": settings.UserID, "chat_id": settings.ChatID}
+	_, err := m.settingsCollection.UpdateOne(ctx, filter, bson.M{"$set": settings}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (m *MongoDBStorage) GetActiveGame(chatId uuid.UUID) (*utils.Game, error) {
+	ctx := context.Background()
+	game := utils.Game{}
+	err := m.gamesCollection.FindOne(ctx, bson.M{"chat_id": chatId, "active": true}).Decode(&game)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// StartGame stores a new game, it returns false if the chat already has an active game
+func (m *MongoDBStorage) StartGame(game utils.Game) (bool, error) {
+	ctx := context.Background()
+	_, err := m.gamesCollection.InsertOne(ctx, game)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetGameWords starts the game with its words, it returns false if the game was given up in the meantime
+func (m *MongoDBStorage) SetGameWords(gameId uuid.UUID, words []string, deadline time.Time) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{"_id": gameId, "active": true, "status": utils.GAME_STARTING}
+	update := bson.M{"$set": bson.M{
+		"words":    words,
+		"hints":    make([]int, len(words)),
+		"deadline": deadline,
+		"status":   utils.GAME_RUNNING,
+	}}
+	result, err := m.gamesCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// AddGameGuess atomically records a guess, if the word was already guessed or the game is over nil is returned
+func (m *MongoDBStorage) AddGameGuess(gameId uuid.UUID, guess utils.GameGuess) (*utils.Game, error) {
+	ctx := context.Background()
+	filter := bson.M{
+		"_id":          gameId,
+		"active":       true,
+		"status":       utils.GAME_RUNNING,
+		"deadline":     bson.M{"$gt": guess.Timestamp},
+		"words":        guess.Word,
+		"guesses.word": bson.M{"$ne": guess.Word},
+	}
+	update := bson.M{"$push": bson.M{"guesses": guess}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	game := utils.Game{}
+	err := m.gamesCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&game)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// FinishGame ends an active game, it returns false if the game was already finished
+func (m *MongoDBStorage) FinishGame(gameId uuid.UUID, status utils.GameStatus, finishedAt time.Time) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{"_id": gameId, "active": true}
+	update := bson.M{
+		"$set":   bson.M{"status": status, "finished_at": finishedAt},
+		"$unset": bson.M{"active": ""},
+	}
+	result, err := m.gamesCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// ClaimExpiredGame atomically finishes the next game whose deadline has passed, so only one replica announces it
+func (m *MongoDBStorage) ClaimExpiredGame(now time.Time) (*utils.Game, error) {
+	ctx := context.Background()
+	filter := bson.M{"active": true, "deadline": bson.M{"$lte": now}}
+	update := bson.M{
+		"$set":   bson.M{"status": utils.GAME_EXPIRED, "finished_at": now},
+		"$unset": bson.M{"active": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	game := utils.Game{}
+	err := m.gamesCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&game)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// AddGameHint reveals one more letter of a word of a running game
+func (m *MongoDBStorage) AddGameHint(gameId uuid.UUID, wordIndex int) (*utils.Game, error) {
+	ctx := context.Background()
+	filter := bson.M{"_id": gameId, "active": true, "status": utils.GAME_RUNNING}
+	update := bson.M{"$inc": bson.M{fmt.Sprintf("hints.%d", wordIndex): 1}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	game := utils.Game{}
+	err := m.gamesCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&game)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// RecordGameScores adds the results of a finished game to the all-time scores of the players
+func (m *MongoDBStorage) RecordGameScores(chatId uuid.UUID, scores []utils.GameScore) error {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(scores))
+	for _, score := range scores {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"chat_id": chatId, "user_id": score.UserID}).
+			SetUpdate(bson.M{"$inc": bson.M{"words": score.Words, "games": score.Games, "wins": score.Wins}}).
+			SetUpsert(true))
+	}
+
+	ctx := context.Background()
+	_, err := m.scoresCollection.BulkWrite(ctx, models)
+	return err
+}
+
+func (m *MongoDBStorage) GetGameLeaderboard(chatId uuid.UUID, limit int) ([]utils.GameScore, error) {
+	filter := bson.M{"chat_id": chatId}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "words", Value: -1}, {Key: "wins", Value: -1}}).
+		SetLimit(int64(limit))
+
+	ctx := context.Background()
+	result, err := m.scoresCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := []utils.GameScore{}
+	err = result.All(ctx, &scores)
+	if err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+func (m *MongoDBStorage) GetActiveQuiz(chatId uuid.UUID) (*utils.Quiz, error) {
+	ctx := context.Background()
+	quiz := utils.Quiz{}
+	err := m.quizzesCollection.FindOne(ctx, bson.M{"chat_id": chatId, "active": true}).Decode(&quiz)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+// StartQuiz stores a new quiz, it returns false if the chat already has an active quiz
+func (m *MongoDBStorage) StartQuiz(quiz utils.Quiz) (bool, error) {
+	ctx := context.Background()
+	_, err := m.quizzesCollection.InsertOne(ctx, quiz)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetQuizQuestions starts the quiz with its questions, it returns false if the quiz was given up in the meantime
+func (m *MongoDBStorage) SetQuizQuestions(quizId uuid.UUID, questions []utils.QuizQuestion, startedAt time.Time, deadline time.Time) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{"_id": quizId, "active": true, "status": utils.QUIZ_STARTING}
+	update := bson.M{"$set": bson.M{
+		"questions":           questions,
+		"current":             0,
+		"question_started_at": startedAt,
+		"deadline":            deadline,
+		"status":              utils.QUIZ_RUNNING,
+	}}
+	result, err := m.quizzesCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// AddQuizAnswer atomically records an answer to the current question, it returns false
+// if the player already answered it or the question is over
+func (m *MongoDBStorage) AddQuizAnswer(quizId uuid.UUID, answer utils.QuizAnswer) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{
+		"_id":      quizId,
+		"active":   true,
+		"status":   utils.QUIZ_RUNNING,
+		"current":  answer.Question,
+		"deadline": bson.M{"$gt": answer.Timestamp},
+		"answers": bson.M{"$not": bson.M{"$elemMatch": bson.M{
+			"question": answer.Question,
+			"user_id":  answer.UserID,
+		}}},
+	}
+	update := bson.M{"$push": bson.M{"answers": answer}}
+
+	result, err := m.quizzesCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// FinishQuiz ends an active quiz, it returns false if the quiz was already finished
+func (m *MongoDBStorage) FinishQuiz(quizId uuid.UUID, status utils.QuizStatus, finishedAt time.Time) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{"_id": quizId, "active": true}
+	update := bson.M{
+		"$set":   bson.M{"status": status, "finished_at": finishedAt},
+		"$unset": bson.M{"active": ""},
+	}
+	result, err := m.quizzesCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// ClaimQuizQuestion atomically moves the next quiz whose question is over to the next question,
+// so only one replica announces it. The quiz is returned as it was before the update
+func (m *MongoDBStorage) ClaimQuizQuestion(now time.Time, deadline time.Time) (*utils.Quiz, error) {
+	ctx := context.Background()
+	filter := bson.M{"active": true, "deadline": bson.M{"$lte": now}}
+	update := bson.M{
+		"$inc": bson.M{"current": 1},
+		"$set": bson.M{"question_started_at": now, "deadline": deadline},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	quiz := utils.Quiz{}
+	err := m.quizzesCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&quiz)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+// ImportMessages inserts messages that are not stored yet. Messages with a known id only get the
+// reply count of their thread and the sender, if the message was imported without a user before.
+// It returns the number of inserted messages
+func (m *MongoDBStorage) ImportMessages(messages []utils.Message) (int64, error) {
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	system := uuid.MustParse(utils.AIChat)
+	models := make([]mongo.WriteModel, 0, len(messages))
+	for _, message := range messages {
+		update, err := importUpdate(message)
+		if err != nil {
+			return 0, err
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": message.ID}).
+			SetUpdate(update).
+			SetUpsert(true))
+
+		// the author was not a user when the message was imported the last time
+		if message.SenderID != system {
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": message.ID, "sender": system}).
+				SetUpdate(bson.M{"$set": bson.M{"sender": message.SenderID, "content": message.Content, "kind": message.Kind}}))
+		}
+	}
+
+	ctx := context.Background()
+	result, err := m.messagesCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, err
+	}
+	return result.UpsertedCount, nil
+}
+
+// importUpdate inserts the message if it is new. The replies of a thread can be imported in
+// several runs and live replies are counted as well, so the reply count only grows
+func importUpdate(message utils.Message) (bson.M, error) {
+	data, err := bson.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	document := bson.M{}
+	err = bson.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+	delete(document, "reply_count")
+	delete(document, "last_reply_at")
+
+	update := bson.M{"$setOnInsert": document}
+	if message.ReplyCount > 0 {
+		update["$max"] = bson.M{"reply_count": message.ReplyCount, "last_reply_at": message.LastReplyAt}
+	}
+	return update, nil
+}
+
+// SetMessagePreviews stores the link previews of a message, it returns false if the content was edited in the meantime
+func (m *MongoDBStorage) SetMessagePreviews(messageId uuid.UUID, content string, previews []utils.LinkPreview) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{"_id": messageId, "content": content, "deleted": false}
+
+	update := bson.M{"$set": bson.M{"previews": previews, "updatedAt": time.Now()}}
+	if len(previews) == 0 {
+		update = bson.M{"$unset": bson.M{"previews": ""}, "$set": bson.M{"updatedAt": time.Now()}}
+	}
+
+	result, err := m.messagesCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// SavePollVote stores the vote of a user, it returns false if the user has already voted
+func (m *MongoDBStorage) SavePollVote(vote utils.PollVote) (bool, error) {
+	ctx := context.Background()
+	_, err := m.votesCollection.InsertOne(ctx, vote)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *MongoDBStorage) DeletePollVote(messageId uuid.UUID, userId uuid.UUID) error {
+	ctx := context.Background()
+	_, err := m.votesCollection.DeleteOne(ctx, bson.M{"message_id": messageId, "user_id": userId})
+	return err
+}
+
+// CountPollVote adds the vote to the tallies of the poll, it returns false if the poll is closed
+func (m *MongoDBStorage) CountPollVote(vote utils.PollVote, anonymous bool) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{
+		"_id":         vote.MessageID,
+		"deleted":     false,
+		"poll.closed": false,
+		"$or": bson.A{
+			bson.M{"poll.closes_at": nil},
+			bson.M{"poll.closes_at": bson.M{"$gt": vote.Timestamp}},
+		},
+	}
+
+	inc := bson.M{"poll.voters": 1}
+	push := bson.M{}
+	for _, option := range vote.Options {
+		inc[fmt.Sprintf("poll.options.%d.votes", option)] = 1
+		if !anonymous {
+			push[fmt.Sprintf("poll.options.%d.voters", option)] = vote.UserID
+		}
+	}
+
+	update := bson.M{"$inc": inc, "$set": bson.M{"updatedAt": time.Now()}}
+	if len(push) > 0 {
+		update["$push"] = push
+	}
+
+	result, err := m.messagesCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// ClosePoll freezes the tallies of the poll, it returns false if the poll was already closed
+func (m *MongoDBStorage) ClosePoll(messageId uuid.UUID, closedAt time.Time) (bool, error) {
+	ctx := context.Background()
+	filter := bson.M{"_id": messageId, "poll": bson.M{"$ne": nil}, "poll.closed": false}
+	update := bson.M{"$set": bson.M{
+		"poll.closed":    true,
+		"poll.closed_at": closedAt,
+		"updatedAt":      closedAt,
+	}}
+
+	result, err := m.messagesCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSearchHit_DecodesScore(t *testing.T) {
+	id := uuid.New()
+	document, err := bson.Marshal(bson.M{"_id": id, "content": "hi", "score": 1.5})
+	assert.NoError(t, err)
+
+	hit := searchHit{}
+	assert.NoError(t, bson.Unmarshal(document, &hit))
+
+	assert.Equal(t, id, hit.Message.ID)
+	assert.Equal(t, "hi", hit.Message.Content)
+	assert.Equal(t, utils.KIND_TEXT, hit.Message.Kind)
+	assert.Equal(t, 1.5, hit.Score)
+}
+
+func TestMessageEdit_OnlySetsEditedFields(t *testing.T) {
+	message := utils.Message{ID: uuid.New(), SenderID: uuid.New(), Content: "$content", Kind: utils.KIND_TEXT, Edited: true, EditCount: 1}
+
+	pipeline := messageEdit(message)
+
+	assert.Len(t, pipeline, 1)
+	set := pipeline[0].(bson.M)["$set"].(bson.M)
+	assert.ElementsMatch(t, []string{"content", "media", "edited", "edit_count", "edited_at", "updatedAt", "kind", "payload", "previews"}, keys(set))
+
+	// user input is never read as a field path
+	assert.Equal(t, bson.M{"$literal": "$content"}, set["content"])
+	assert.Equal(t, "$$REMOVE", set["edited_at"])
+	assert.Equal(t, "$$REMOVE", set["payload"])
+}
+
+func TestImportUpdate_ReplyCountOnlyGrows(t *testing.T) {
+	lastReply := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	message := utils.Message{ID: uuid.New(), Content: "question", Kind: utils.KIND_TEXT, ReplyCount: 2, LastReplyAt: &lastReply}
+
+	update, err := importUpdate(message)
+
+	assert.NoError(t, err)
+	// a field can not be part of $setOnInsert and $max at the same time
+	inserted := update["$setOnInsert"].(bson.M)
+	assert.NotContains(t, inserted, "reply_count")
+	assert.NotContains(t, inserted, "last_reply_at")
+	assert.Equal(t, "question", inserted["content"])
+	assert.Equal(t, bson.M{"reply_count": 2, "last_reply_at": &lastReply}, update["$max"])
+
+	update, err = importUpdate(utils.Message{ID: uuid.New(), Content: "reply", Kind: utils.KIND_TEXT})
+	assert.NoError(t, err)
+	assert.NotContains(t, update, "$max")
+}
+
+func keys(document bson.M) []string {
+	keys := []string{}
+	for key := range document {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,340 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nilspolek/DevOps/Chat/internal/utils"
+	"golang.org/x/net/html"
+)
+
+const (
+	// a preview is only worth it if it is there right after the message
+	FETCH_TIMEOUT = 5 * time.Second
+	MAX_REDIRECTS = 3
+	// the metadata is in the head, the rest of the page is not read
+	MAX_BODY_SIZE = 512 * 1024
+	CACHE_TTL     = time.Hour
+	// failures may be temporary, they are retried sooner
+	ERROR_CACHE_TTL = time.Minute
+	CACHE_SIZE      = 1000
+
+	MAX_TITLE_LENGTH       = 200
+	MAX_DESCRIPTION_LENGTH = 500
+)
+
+// DEFAULT_BLOCKLIST contains the loopback, private, link local and other internal ranges,
+// so messages can not be used to reach services inside of the cluster
+var DEFAULT_BLOCKLIST = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+}
+
+var (
+	logger = utils.GetLogger("unfurl")
+
+	ErrBlocked = errors.New("address is blocked")
+)
+
+type cachedPreview struct {
+	preview *utils.LinkPreview
+	err     error
+	time    time.Time
+}
+
+func (c cachedPreview) ttl() time.Duration {
+	if c.err != nil {
+		return ERROR_CACHE_TTL
+	}
+	return CACHE_TTL
+}
+
+type Unfurler struct {
+	client    *http.Client
+	blocklist []netip.Prefix
+	cache     map[string]cachedPreview
+	mu        sync.RWMutex
+}
+
+// New creates an unfurler that refuses to connect to addresses in the blocklist.
+// Entries are CIDR ranges or single addresses
+func New(blocklist []string) (*Unfurler, error) {
+	u := &Unfurler{
+		blocklist: make([]netip.Prefix, 0, len(blocklist)),
+		cache:     map[string]cachedPreview{},
+		mu:        sync.RWMutex{},
+	}
+
+	for _, entry := range blocklist {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid blocklist entry %q", entry)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		u.blocklist = append(u.blocklist, prefix.Masked())
+	}
+
+	// the address is checked after it was resolved, so a host name can not point to a blocked address
+	dialer := &net.Dialer{
+		Timeout: FETCH_TIMEOUT,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if u.Blocked(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrBlocked, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	u.client = &http.Client{
+		Timeout: FETCH_TIMEOUT,
+		Transport: &http.Transport{
+			// a proxy would connect on our behalf and skip the check of the dialer
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   FETCH_TIMEOUT,
+			ResponseHeaderTimeout: FETCH_TIMEOUT,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= MAX_REDIRECTS {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+
+	return u, nil
+}
+
+// Blocked tells if the unfurler refuses to connect to the address
+func (u *Unfurler) Blocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range u.blocklist {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unfurl returns the preview of the page, pages without a title have no preview.
+// Results are cached by url, including failures, so a link posted many times is fetched once
+func (u *Unfurler) Unfurl(link string) (*utils.LinkPreview, error) {
+	u.mu.RLock()
+	cached, ok := u.cache[link]
+	u.mu.RUnlock()
+
+	if ok && time.Since(cached.time) < cached.ttl() {
+		return cached.preview, cached.err
+	}
+
+	preview, err := u.fetch(link)
+	if err != nil {
+		logger.Debug().Err(err).Str("url", link).Msg("failed to unfurl link")
+	}
+
+	u.mu.Lock()
+	u.evict()
+	u.cache[link] = cachedPreview{preview: preview, err: err, time: time.Now()}
+	u.mu.Unlock()
+
+	return preview, err
+}
+
+// evict makes room for a new entry, it has to be called with the lock held
+func (u *Unfurler) evict() {
+	if len(u.cache) < CACHE_SIZE {
+		return
+	}
+
+	for link, cached := range u.cache {
+		if time.Since(cached.time) >= cached.ttl() {
+			delete(u.cache, link)
+		}
+	}
+
+	// drop any entry if none has expired yet
+	for link := range u.cache {
+		if len(u.cache) < CACHE_SIZE {
+			break
+		}
+		delete(u.cache, link)
+	}
+}
+
+func (u *Unfurler) fetch(link string) (*utils.LinkPreview, error) {
+	target, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q", target.Scheme)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), FETCH_TIMEOUT)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "CommzBot/1.0 (link preview)")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, nil
+	}
+
+	preview, err := parse(io.LimitReader(resp.Body, MAX_BODY_SIZE), resp.Request.URL)
+	if preview != nil {
+		// clients find the preview by the link of the message, not by the page it redirected to
+		preview.URL = link
+	}
+	return preview, err
+}
+
+// parse reads the OpenGraph and Twitter card tags of the head, the title tag is used if there are none
+func parse(r io.Reader, base *url.URL) (*utils.LinkPreview, error) {
+	meta := map[string]string{}
+	title := ""
+
+	tokenizer := html.NewTokenizer(r)
+head:
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() == io.EOF {
+				break
+			}
+			return nil, tokenizer.Err()
+		}
+
+		token := tokenizer.Token()
+		if tokenType == html.EndTagToken && token.Data == "head" {
+			break
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		switch token.Data {
+		case "body":
+			// pages without a closing head tag start the body right away
+			break head
+		case "title":
+			if tokenizer.Next() == html.TextToken && title == "" {
+				title = string(tokenizer.Text())
+			}
+		case "meta":
+			key, content := "", ""
+			for _, attr := range token.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "property", "name":
+					key = strings.ToLower(attr.Val)
+				case "content":
+					content = attr.Val
+				}
+			}
+			if _, ok := meta[key]; !ok && key != "" {
+				meta[key] = content
+			}
+		}
+	}
+
+	preview := utils.LinkPreview{
+		URL:         base.String(),
+		Title:       truncate(first(meta["og:title"], meta["twitter:title"], title), MAX_TITLE_LENGTH),
+		Description: truncate(first(meta["og:description"], meta["twitter:description"], meta["description"]), MAX_DESCRIPTION_LENGTH),
+		Image:       resolve(base, first(meta["og:image"], meta["og:image:url"], meta["twitter:image"], meta["twitter:image:src"])),
+		SiteName:    truncate(first(meta["og:site_name"], base.Hostname()), MAX_TITLE_LENGTH),
+	}
+
+	if preview.Title == "" {
+		return nil, nil
+	}
+	return &preview, nil
+}
+
+func first(values ...string) string {
+	for _, value := range values {
+		value = strings.Join(strings.Fields(value), " ")
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// resolve makes relative image urls absolute, images with other schemes are left out
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	image, err := base.Parse(ref)
+	if err != nil || (image.Scheme != "http" && image.Scheme != "https") {
+		return ""
+	}
+	return image.String()
+}
+
+func truncate(value string, length int) string {
+	if utf8.RuneCountInString(value) <= length {
+		return value
+	}
+	runes := []rune(value)
+	return strings.TrimSpace(string(runes[:length-1])) + "…"
+}
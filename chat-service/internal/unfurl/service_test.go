@@ -0,0 +1,102 @@
+package unfurl
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	page := `<!DOCTYPE html><html><head>
+		<title>Fallback</title>
+		<meta property="og:title" content="Commz &amp; friends">
+		<meta name="twitter:description" content="  Chat   with
+			everyone ">
+		<meta property="og:image" content="/images/logo.png">
+		</head><body><meta property="og:site_name" content="ignored"></body></html>`
+	base, _ := url.Parse("https://commz.de/about")
+
+	preview, err := parse(strings.NewReader(page), base)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Commz & friends", preview.Title)
+	assert.Equal(t, "Chat with everyone", preview.Description)
+	assert.Equal(t, "https://commz.de/images/logo.png", preview.Image)
+	assert.Equal(t, "commz.de", preview.SiteName)
+}
+
+func TestParse_NoTitle(t *testing.T) {
+	base, _ := url.Parse("https://commz.de")
+
+	preview, err := parse(strings.NewReader(`<html><head><meta name="description" content="only text"></head></html>`), base)
+
+	assert.NoError(t, err)
+	assert.Nil(t, preview)
+}
+
+func TestBlocked(t *testing.T) {
+	unfurler, err := New(slices.Concat(DEFAULT_BLOCKLIST, []string{"203.0.113.7"}))
+	assert.NoError(t, err)
+
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "169.254.169.254", "::1", "::ffff:192.168.0.1", "fd00::1", "203.0.113.7"} {
+		assert.True(t, unfurler.Blocked(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{"1.1.1.1", "203.0.113.8", "2606:4700::1111"} {
+		assert.False(t, unfurler.Blocked(netip.MustParseAddr(addr)), addr)
+	}
+
+	_, err = New([]string{"not an address"})
+	assert.Error(t, err)
+}
+
+func TestUnfurl_BlocksLoopback(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head><title>internal</title></head></html>")
+	}))
+	defer server.Close()
+
+	unfurler, err := New(DEFAULT_BLOCKLIST)
+	assert.NoError(t, err)
+
+	preview, err := unfurler.Unfurl(server.URL)
+
+	assert.Nil(t, preview)
+	assert.True(t, errors.Is(err, ErrBlocked))
+	assert.Equal(t, 0, requests)
+
+	// the failure is cached
+	_, cached := unfurler.cache[server.URL]
+	assert.True(t, cached)
+}
+
+func TestUnfurl_CachesPreviews(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><meta property="og:title" content="Commz"></head></html>`)
+	}))
+	defer server.Close()
+
+	unfurler, err := New(nil)
+	assert.NoError(t, err)
+
+	for range 2 {
+		preview, err := unfurler.Unfurl(server.URL + "/page")
+		assert.NoError(t, err)
+		assert.Equal(t, "Commz", preview.Title)
+		assert.Equal(t, server.URL+"/page", preview.URL)
+	}
+	assert.Equal(t, 1, requests)
+}
@@ -1,13 +1,15 @@
 package utils
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 type Storage interface {
-	GetChats(user uuid.UUID) ([]Chat, error)
+	GetChats(user uuid.UUID, opts ChatListOptions) ([]Chat, error)
 	GetChat(id uuid.UUID) (*Chat, error)
 	GetChatMessages(chatId uuid.UUID, limit, offset int) ([]Message, error)
 	MemberOfChat(userId uuid.UUID, chatId uuid.UUID) error
@@ -18,16 +20,78 @@ type Storage interface {
 	DeleteChat(chat uuid.UUID) error
 	UpdateMessage(message Message) error
 	DeleteMessage(message uuid.UUID) error
+	AddReaction(messageId uuid.UUID, reaction Reaction) error
+	RemoveReaction(messageId uuid.UUID, userId uuid.UUID, emoji string) error
+	GetThread(rootId uuid.UUID) ([]Message, error)
+	AddThreadReply(rootId uuid.UUID, timestamp time.Time) error
+	MarkMessageRead(messageId uuid.UUID, userId uuid.UUID, readAt time.Time) error
+	MarkChatRead(chatId uuid.UUID, userId uuid.UUID, upTo time.Time, readAt time.Time) (int64, error)
+	SaveEvent(event Event) error
+	GetChatIDs(user uuid.UUID) ([]uuid.UUID, error)
+	SearchMessages(query SearchQuery) ([]SearchResult, int64, error)
+	GetChatMessagesFrom(chatId uuid.UUID, cursor MessageCursor, older bool, inclusive bool, limit int) ([]Message, error)
+	SaveRevision(revision Revision) error
+	GetRevisions(messageId uuid.UUID) ([]Revision, error)
+	SetChatRole(chatId uuid.UUID, userId uuid.UUID, role ChatRole) error
+	AddChatMembers(chatId uuid.UUID, members []uuid.UUID) error
+	RemoveChatMember(chatId uuid.UUID, userId uuid.UUID) error
+	AddPin(chatId uuid.UUID, pin Pin, maxPins int) (bool, error)
+	RemovePin(chatId uuid.UUID, messageId uuid.UUID) (bool, error)
+	SaveScheduledMessage(message ScheduledMessage) error
+	GetScheduledMessage(id uuid.UUID) (*ScheduledMessage, error)
+	GetScheduledMessages(userId uuid.UUID) ([]ScheduledMessage, error)
+	UpdateScheduledMessage(message ScheduledMessage) (bool, error)
+	DeleteScheduledMessage(id uuid.UUID, userId uuid.UUID) (bool, error)
+	ClaimScheduledMessage(now time.Time, lease time.Duration) (*ScheduledMessage, error)
+	FinishScheduledMessage(id uuid.UUID, failure string) error
+	SetChatRetention(chatId uuid.UUID, retention *Retention) error
+	GetChatsWithRetention() ([]Chat, error)
+	ExpireMessages(chatId uuid.UUID, before time.Time) (int64, error)
+	SaveInvite(invite Invite) error
+	GetInvite(id uuid.UUID) (*Invite, error)
+	GetInvites(chatId uuid.UUID) ([]Invite, error)
+	RevokeInvite(chatId uuid.UUID, inviteId uuid.UUID) (bool, error)
+	UseInvite(inviteId uuid.UUID, now time.Time) (bool, error)
+	GetChatSettings(userId uuid.UUID) ([]ChatSettings, error)
+	SaveChatSettings(settings ChatSettings) error
+	GetActiveGame(chatId uuid.UUID) (*Game, error)
+	StartGame(game Game) (bool, error)
+	SetGameWords(gameId uuid.UUID, words []string, deadline time.Time) (bool, error)
+	AddGameGuess(gameId uuid.UUID, guess GameGuess) (*Game, error)
+	FinishGame(gameId uuid.UUID, status GameStatus, finishedAt time.Time) (bool, error)
+	ClaimExpiredGame(now time.Time) (*Game, error)
+	AddGameHint(gameId uuid.UUID, wordIndex int) (*Game, error)
+	RecordGameScores(chatId uuid.UUID, scores []GameScore) error
+	GetGameLeaderboard(chatId uuid.UUID, limit int) ([]GameScore, error)
+	ImportMessages(messages []Message) (int64, error)
+	SetMessagePreviews(messageId uuid.UUID, content string, previews []LinkPreview) (bool, error)
+	SavePollVote(vote PollVote) (bool, error)
+	DeletePollVote(messageId uuid.UUID, userId uuid.UUID) error
+	CountPollVote(vote PollVote, anonymous bool) (bool, error)
+	ClosePoll(messageId uuid.UUID, closedAt time.Time) (bool, error)
+	GetActiveQuiz(chatId uuid.UUID) (*Quiz, error)
+	StartQuiz(quiz Quiz) (bool, error)
+	SetQuizQuestions(quizId uuid.UUID, questions []QuizQuestion, startedAt time.Time, deadline time.Time) (bool, error)
+	AddQuizAnswer(quizId uuid.UUID, answer QuizAnswer) (bool, error)
+	FinishQuiz(quizId uuid.UUID, status QuizStatus, finishedAt time.Time) (bool, error)
+	ClaimQuizQuestion(now time.Time, deadline time.Time) (*Quiz, error)
 }
 
 type AuthService interface {
 	VerifyToken(token string) (*User, error)
 	Exists(ids ...uuid.UUID) (bool, error)
+	GetUsers(ids ...uuid.UUID) (map[uuid.UUID]User, error)
+	GetUsersByEmail(emails ...string) (map[string]User, error)
 }
 
 type AiService interface {
 	AskAI(prompt string, response func(response GenerateResponse)) error
-	GuessWords(topic string) ([]string, error)
+	GuessWords(topic string, count int, difficulty string) ([]string, error)
+	GenerateQuiz(topic string, count int) ([]QuizQuestion, error)
+}
+
+type Unfurler interface {
+	Unfurl(url string) (*LinkPreview, error)
 }
 
 type Message struct {
@@ -42,6 +106,203 @@ type Message struct {
 	Read      bool        `json:"read" bson:"read"`
 	ReplyTo   *uuid.UUID  `json:"reply_to" bson:"reply_to"`
 	Deleted   bool        `json:"deleted" bson:"deleted"`
+	Reactions []Reaction  `json:"reactions" bson:"reactions,omitempty"`
+
+	// ThreadID is the id of the root message of the thread this message is a reply in
+	ThreadID *uuid.UUID `json:"thread_id" bson:"thread_id,omitempty"`
+	// ReplyCount and LastReplyAt are only maintained on the root message of a thread
+	ReplyCount  int        `json:"reply_count" bson:"reply_count,omitempty"`
+	LastReplyAt *time.Time `json:"last_reply_at" bson:"last_reply_at,omitempty"`
+
+	Edited    bool       `json:"edited" bson:"edited"`
+	EditCount int        `json:"edit_count" bson:"edit_count"`
+	EditedAt  *time.Time `json:"edited_at" bson:"edited_at,omitempty"`
+
+	// ReadBy maps the id of every member that has read the message to the time it was read.
+	// Read is set as soon as any member other than the sender has read the message
+	ReadBy map[string]time.Time `json:"read_by" bson:"read_by,omitempty"`
+
+	// Poll is set if the message is a poll, the content holds its question
+	Poll *Poll `json:"poll,omitempty" bson:"poll,omitempty"`
+
+	// ForwardedFrom is set if the message is a forwarded copy of another message
+	ForwardedFrom *ForwardedFrom `json:"forwarded_from,omitempty" bson:"forwarded_from,omitempty"`
+
+	// Previews of the links in the content, they are added shortly after the message is sent
+	Previews []LinkPreview `json:"previews,omitempty" bson:"previews,omitempty"`
+
+	// Kind tells clients how to render the message, Payload holds the data of the kind
+	Kind    MessageKind     `json:"kind" bson:"kind"`
+	Payload *MessagePayload `json:"payload,omitempty" bson:"payload,omitempty"`
+}
+
+// LinkPreview holds the OpenGraph or Twitter card metadata of a link
+type LinkPreview struct {
+	URL         string `json:"url" bson:"url"`
+	Title       string `json:"title" bson:"title"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+	Image       string `json:"image,omitempty" bson:"image,omitempty"`
+	SiteName    string `json:"site_name,omitempty" bson:"site_name,omitempty"`
+}
+
+// UnmarshalBSON reads messages stored before messages had a kind as text messages
+func (m *Message) UnmarshalBSON(data []byte) error {
+	type message Message
+	err := bson.Unmarshal(data, (*message)(m))
+	if err != nil {
+		return err
+	}
+
+	if m.Kind == "" {
+		m.Kind = KIND_TEXT
+	}
+	return nil
+}
+
+type MessageKind string
+
+const (
+	// KIND_TEXT messages only have content
+	KIND_TEXT MessageKind = "text"
+	// KIND_MEDIA messages have media, the content is the caption
+	KIND_MEDIA MessageKind = "media"
+	// KIND_SYSTEM messages are posted by the chat service about events of the chat
+	KIND_SYSTEM MessageKind = "system"
+	// KIND_COMMAND messages invoke a slash command
+	KIND_COMMAND MessageKind = "command"
+	// KIND_AI messages are the answers in the AI chat
+	KIND_AI MessageKind = "ai"
+	// KIND_POLL messages hold a poll, the content is its question
+	KIND_POLL MessageKind = "poll"
+)
+
+// MessagePayload holds the typed data of a message, only the field of the kind of the message is set.
+// Text, media and poll messages keep their data in content, media and poll
+type MessagePayload struct {
+	System  *SystemPayload  `json:"system,omitempty" bson:"system,omitempty"`
+	Command *CommandPayload `json:"command,omitempty" bson:"command,omitempty"`
+	AI      *AIPayload      `json:"ai,omitempty" bson:"ai,omitempty"`
+}
+
+type SystemEvent string
+
+const (
+	EVENT_COMMAND_REPLY     SystemEvent = "command_reply"
+	EVENT_MEMBER_JOINED     SystemEvent = "member_joined"
+	EVENT_MEMBERS_ADDED     SystemEvent = "members_added"
+	EVENT_MEMBER_REMOVED    SystemEvent = "member_removed"
+	EVENT_MEMBER_LEFT       SystemEvent = "member_left"
+	EVENT_MESSAGE_PINNED    SystemEvent = "message_pinned"
+	EVENT_RETENTION_CHANGED SystemEvent = "retention_changed"
+)
+
+// SystemPayload describes the event a system message is about
+type SystemPayload struct {
+	Event SystemEvent `json:"event" bson:"event"`
+	// Actor is the user that caused the event
+	Actor *uuid.UUID `json:"actor,omitempty" bson:"actor,omitempty"`
+	// Users are the users the event is about, e.g. the added members or the new owner
+	Users []uuid.UUID `json:"users,omitempty" bson:"users,omitempty"`
+	// MessageID is the message the event is about, e.g. the pinned message
+	MessageID *uuid.UUID `json:"message_id,omitempty" bson:"message_id,omitempty"`
+	// Command is set on replies of a command
+	Command string `json:"command,omitempty" bson:"command,omitempty"`
+}
+
+// CommandPayload is the parsed invocation of a command, the content holds the raw arguments
+type CommandPayload struct {
+	Name string   `json:"name" bson:"name"`
+	Args []string `json:"args" bson:"args"`
+}
+
+// AIPayload tells if the answer of the AI is still being generated
+type AIPayload struct {
+	Complete bool `json:"complete" bson:"complete"`
+	Failed   bool `json:"failed,omitempty" bson:"failed,omitempty"`
+}
+
+// ForwardedFrom references the original message of a forwarded copy
+type ForwardedFrom struct {
+	MessageID uuid.UUID `json:"message_id" bson:"message_id"`
+	SenderID  uuid.UUID `json:"sender" bson:"sender"`
+	ChatID    uuid.UUID `json:"chat_id" bson:"chat_id"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+}
+
+// ReadAt returns when the user has read the message.
+// Messages stored before read receipts only carry the shared read flag,
+// those count as read by every member but have no read time.
+func (m *Message) ReadAt(userId uuid.UUID) (*time.Time, bool) {
+	if readAt, ok := m.ReadBy[userId.String()]; ok {
+		return &readAt, true
+	}
+	return nil, len(m.ReadBy) == 0 && m.Read
+}
+
+// Revision is a previous version of an edited message
+type Revision struct {
+	ID        uuid.UUID   `json:"id" bson:"_id"`
+	MessageID uuid.UUID   `json:"message_id" bson:"message_id"`
+	Content   string      `json:"content" bson:"content"`
+	Media     []uuid.UUID `json:"media" bson:"media"`
+	// Timestamp is the time this version was written, EditedAt the time it was replaced
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+	EditedAt  time.Time `json:"edited_at" bson:"edited_at"`
+}
+
+// MessageHistory is the current version of a message with all previous versions in chronological order
+type MessageHistory struct {
+	Message   Message    `json:"message"`
+	Revisions []Revision `json:"revisions"`
+}
+
+// Receipt is the read state of a message for a single chat member
+type Receipt struct {
+	UserID uuid.UUID  `json:"user"`
+	Read   bool       `json:"read"`
+	ReadAt *time.Time `json:"read_at"`
+}
+
+// Poll is attached to a message. The tallies are counted on the message,
+// so clients receive them with every update of the message
+type Poll struct {
+	Question       string       `json:"question" bson:"question"`
+	Options        []PollOption `json:"options" bson:"options"`
+	MultipleChoice bool         `json:"multiple_choice" bson:"multiple_choice"`
+	// Anonymous polls only count the votes, the voters of the options are not kept
+	Anonymous bool       `json:"anonymous" bson:"anonymous"`
+	ClosesAt  *time.Time `json:"closes_at" bson:"closes_at"`
+	Closed    bool       `json:"closed" bson:"closed"`
+	ClosedAt  *time.Time `json:"closed_at" bson:"closed_at"`
+	// Voters is the number of users that have voted
+	Voters int `json:"voters" bson:"voters"`
+}
+
+// Open reports if votes are still accepted
+func (p *Poll) Open(now time.Time) bool {
+	return !p.Closed && (p.ClosesAt == nil || now.Before(*p.ClosesAt))
+}
+
+type PollOption struct {
+	Text   string      `json:"text" bson:"text"`
+	Votes  int         `json:"votes" bson:"votes"`
+	Voters []uuid.UUID `json:"voters" bson:"voters"`
+}
+
+// PollVote is the vote of a user, every user can vote once per poll
+type PollVote struct {
+	ID        uuid.UUID `json:"id" bson:"_id"`
+	MessageID uuid.UUID `json:"message_id" bson:"message_id"`
+	UserID    uuid.UUID `json:"user_id" bson:"user_id"`
+	Options   []int     `json:"options" bson:"options"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+}
+
+// Reaction is a single emoji reaction of a user to a message
+type Reaction struct {
+	Emoji     string    `json:"emoji" bson:"emoji"`
+	UserID    uuid.UUID `json:"user" bson:"user"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
 }
 
 type Chat struct {
@@ -52,6 +313,358 @@ type Chat struct {
 	CreatorID  uuid.UUID   `json:"creator_id" bson:"creator_id"`
 	CreatedAt  time.Time   `json:"created_at" bson:"created_at"`
 	LastActive time.Time   `json:"last_active" bson:"last_active"`
+	// Roles maps member ids to their role, members without an entry are plain members
+	Roles map[string]ChatRole `json:"roles" bson:"roles,omitempty"`
+	Pins  []Pin               `json:"pins" bson:"pins,omitempty"`
+	// Retention limits how long messages are kept, chats without retention keep their history forever
+	Retention *Retention `json:"retention" bson:"retention,omitempty"`
+
+	// UnreadCount, LastMessage and LastReadAt are computed for the user requesting the chat list
+	UnreadCount int        `json:"unread_count" bson:"-"`
+	LastMessage *Message   `json:"last_message" bson:"-"`
+	LastReadAt  *time.Time `json:"last_read_at" bson:"-"`
+	// Settings are the personal settings of the requesting user, Muted tells clients to suppress notifications
+	Settings *ChatSettings `json:"settings" bson:"-"`
+	Muted    bool          `json:"muted" bson:"-"`
+}
+
+// ChatSettings personalise a chat for a single user
+type ChatSettings struct {
+	ChatID     uuid.UUID  `json:"chat_id" bson:"chat_id"`
+	UserID     uuid.UUID  `json:"user_id" bson:"user_id"`
+	MutedUntil *time.Time `json:"muted_until" bson:"muted_until"`
+	Archived   bool       `json:"archived" bson:"archived"`
+	Favourite  bool       `json:"favourite" bson:"favourite"`
+	// SortOrder places the chat in the chat list, chats without a sort order come last
+	SortOrder *int      `json:"sort_order" bson:"sort_order"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// MutedAt reports if notifications of the chat are muted at the given time
+func (s *ChatSettings) MutedAt(now time.Time) bool {
+	return s != nil && s.MutedUntil != nil && now.Before(*s.MutedUntil)
+}
+
+// Retention is the policy after which messages of a chat are deleted
+type Retention struct {
+	// Days after which messages are deleted
+	Days int `json:"days,omitempty" bson:"days,omitempty"`
+	// MessageTTL makes messages disappear this many seconds after they were sent
+	MessageTTL int `json:"message_ttl,omitempty" bson:"message_ttl,omitempty"`
+}
+
+// MaxAge returns the age after which messages are deleted, zero if messages are kept forever
+func (r *Retention) MaxAge() time.Duration {
+	if r == nil {
+		return 0
+	}
+
+	days := time.Duration(r.Days) * 24 * time.Hour
+	ttl := time.Duration(r.MessageTTL) * time.Second
+	if days == 0 || ttl > 0 && ttl < days {
+		return ttl
+	}
+	return days
+}
+
+// Pin is a message that is pinned to the top of a chat
+type Pin struct {
+	MessageID uuid.UUID `json:"message_id" bson:"message_id"`
+	PinnedBy  uuid.UUID `json:"pinned_by" bson:"pinned_by"`
+	PinnedAt  time.Time `json:"pinned_at" bson:"pinned_at"`
+}
+
+type PinnedMessage struct {
+	Pin
+	Message Message `json:"message"`
+}
+
+// Invite allows users to join a group chat with a signed token
+type Invite struct {
+	ID        uuid.UUID  `json:"id" bson:"_id"`
+	ChatID    uuid.UUID  `json:"chat_id" bson:"chat_id"`
+	CreatedBy uuid.UUID  `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at" bson:"expires_at"`
+	// MaxUses limits how often the invite can be used, zero allows unlimited uses
+	MaxUses int  `json:"max_uses" bson:"max_uses"`
+	Uses    int  `json:"uses" bson:"uses"`
+	Revoked bool `json:"revoked" bson:"revoked"`
+	// Token is derived from the id, it is not stored
+	Token string `json:"token" bson:"-"`
+}
+
+// Active reports if the invite can still be used
+func (i *Invite) Active(now time.Time) bool {
+	if i.Revoked {
+		return false
+	}
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
+
+// ScheduledMessage is a message that is sent to a chat at a later time.
+// Once it is sent, the message in the chat has the same id
+type ScheduledMessage struct {
+	ID        uuid.UUID       `json:"id" bson:"_id"`
+	ChatID    uuid.UUID       `json:"chat_id" bson:"chat_id"`
+	SenderID  uuid.UUID       `json:"sender_id" bson:"sender_id"`
+	Content   string          `json:"content" bson:"content"`
+	Media     []uuid.UUID     `json:"media" bson:"media"`
+	ReplyTo   *uuid.UUID      `json:"reply_to" bson:"reply_to"`
+	SendAt    time.Time       `json:"send_at" bson:"send_at"`
+	Status    ScheduledStatus `json:"status" bson:"status"`
+	Error     string          `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt time.Time       `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at" bson:"updated_at"`
+	// LockedUntil is set while a dispatcher delivers the message, after that another dispatcher may retry
+	LockedUntil *time.Time `json:"-" bson:"locked_until,omitempty"`
+}
+
+type ScheduledStatus string
+
+const (
+	SCHEDULED_PENDING ScheduledStatus = "pending"
+	SCHEDULED_SENDING ScheduledStatus = "sending"
+	SCHEDULED_FAILED  ScheduledStatus = "failed"
+)
+
+type ChatRole string
+
+const (
+	ROLE_OWNER  ChatRole = "owner"
+	ROLE_ADMIN  ChatRole = "admin"
+	ROLE_MEMBER ChatRole = "member"
+)
+
+var roleRanks = map[ChatRole]int{
+	ROLE_MEMBER: 1,
+	ROLE_ADMIN:  2,
+	ROLE_OWNER:  3,
+}
+
+// RoleOf returns the role of the user in the chat or an empty role if the user is not a member.
+// Chats created before roles existed have no roles stored, there the creator is the owner
+func (c *Chat) RoleOf(userId uuid.UUID) ChatRole {
+	if !slices.Contains(c.Members, userId) {
+		return ""
+	}
+	if role, ok := c.Roles[userId.String()]; ok {
+		return role
+	}
+	if c.CreatorID == userId {
+		return ROLE_OWNER
+	}
+	return ROLE_MEMBER
+}
+
+// AddMembers adds the users with an explicit member role, so a creator that left and joins again
+// is not taken for the owner of a chat from before roles existed
+func (c *Chat) AddMembers(members ...uuid.UUID) {
+	if c.Roles == nil {
+		c.Roles = map[string]ChatRole{}
+	}
+	for _, member := range members {
+		if !slices.Contains(c.Members, member) {
+			c.Members = append(c.Members, member)
+		}
+		if _, ok := c.Roles[member.String()]; !ok {
+			c.Roles[member.String()] = ROLE_MEMBER
+		}
+	}
+}
+
+// HasRole reports if the user has at least the given role in the chat
+func (c *Chat) HasRole(userId uuid.UUID, role ChatRole) bool {
+	return roleRanks[c.RoleOf(userId)] >= roleRanks[role]
+}
+
+// Game is a guessing game in a chat, finished games are kept as a record
+type Game struct {
+	ID         uuid.UUID   `json:"id" bson:"_id"`
+	ChatID     uuid.UUID   `json:"chat_id" bson:"chat_id"`
+	Topic      string      `json:"topic" bson:"topic"`
+	Words      []string    `json:"words" bson:"words"`
+	Guesses    []GameGuess `json:"guesses" bson:"guesses"`
+	Status     GameStatus  `json:"status" bson:"status"`
+	StartedBy  uuid.UUID   `json:"started_by" bson:"started_by"`
+	StartedAt  time.Time   `json:"started_at" bson:"started_at"`
+	Deadline   time.Time   `json:"deadline" bson:"deadline"`
+	FinishedAt *time.Time  `json:"finished_at" bson:"finished_at"`
+	Options    GameOptions `json:"options" bson:"options"`
+	// Hints holds the number of revealed letters for every word
+	Hints []int `json:"hints" bson:"hints"`
+	// Active is only set while the game is not finished, a chat can only have one active game
+	Active bool `json:"-" bson:"active,omitempty"`
+}
+
+// GameOptions are chosen when a game is started
+type GameOptions struct {
+	// Duration in seconds
+	Duration   int    `json:"duration" bson:"duration"`
+	Words      int    `json:"words" bson:"words"`
+	Difficulty string `json:"difficulty" bson:"difficulty"`
+	Hints      bool   `json:"hints" bson:"hints"`
+}
+
+// GameScore is the all-time score of a player in a chat
+type GameScore struct {
+	ChatID uuid.UUID `json:"chat_id" bson:"chat_id"`
+	UserID uuid.UUID `json:"user_id" bson:"user_id"`
+	// Words is the number of guessed words, Wins the number of games with the most guessed words
+	Words       int    `json:"words" bson:"words"`
+	Games       int    `json:"games" bson:"games"`
+	Wins        int    `json:"wins" bson:"wins"`
+	DisplayName string `json:"display_name" bson:"-"`
+}
+
+// GameGuess is a correctly guessed word
+type GameGuess struct {
+	Word      string    `json:"word" bson:"word"`
+	UserID    uuid.UUID `json:"user_id" bson:"user_id"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+}
+
+type GameStatus string
+
+const (
+	GAME_STARTING GameStatus = "starting"
+	GAME_RUNNING  GameStatus = "running"
+	GAME_WON      GameStatus = "won"
+	GAME_EXPIRED  GameStatus = "expired"
+	GAME_FAILED   GameStatus = "failed"
+)
+
+// Quiz is a trivia quiz in a chat, the questions are asked one after another
+type Quiz struct {
+	ID        uuid.UUID      `json:"id" bson:"_id"`
+	ChatID    uuid.UUID      `json:"chat_id" bson:"chat_id"`
+	Topic     string         `json:"topic" bson:"topic"`
+	Questions []QuizQuestion `json:"questions" bson:"questions"`
+	// Current is the index of the question that is asked right now
+	Current           int          `json:"current" bson:"current"`
+	QuestionStartedAt time.Time    `json:"question_started_at" bson:"question_started_at"`
+	Answers           []QuizAnswer `json:"answers" bson:"answers"`
+	Status            QuizStatus   `json:"status" bson:"status"`
+	StartedBy         uuid.UUID    `json:"started_by" bson:"started_by"`
+	StartedAt         time.Time    `json:"started_at" bson:"started_at"`
+	// Deadline is the end of the current question, or of the start while the questions are generated
+	Deadline   time.Time  `json:"deadline" bson:"deadline"`
+	FinishedAt *time.Time `json:"finished_at" bson:"finished_at"`
+	// Active is only set while the quiz is not finished, a chat can only have one active quiz
+	Active bool `json:"-" bson:"active,omitempty"`
+}
+
+// QuizQuestion is a multiple-choice question, Answer is the index of the correct option
+type QuizQuestion struct {
+	Question string   `json:"question" bson:"question"`
+	Options  []string `json:"options" bson:"options"`
+	Answer   int      `json:"answer" bson:"answer"`
+}
+
+// QuizAnswer is the answer of a player to a question, every player can answer once per question
+type QuizAnswer struct {
+	Question  int       `json:"question" bson:"question"`
+	UserID    uuid.UUID `json:"user_id" bson:"user_id"`
+	Option    int       `json:"option" bson:"option"`
+	Correct   bool      `json:"correct" bson:"correct"`
+	Points    int       `json:"points" bson:"points"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+}
+
+type QuizStatus string
+
+const (
+	QUIZ_STARTING QuizStatus = "starting"
+	QUIZ_RUNNING  QuizStatus = "running"
+	QUIZ_FINISHED QuizStatus = "finished"
+	QUIZ_FAILED   QuizStatus = "failed"
+)
+
+// CommandInfo describes a slash command for autocompletion
+type CommandInfo struct {
+	Name        string `json:"name"`
+	Usage       string `json:"usage"`
+	Description string `json:"description"`
+}
+
+// ChatListOptions controls what is loaded when listing the chats of a user
+type ChatListOptions struct {
+	// IncludeMessages embeds the latest messages of every chat
+	IncludeMessages bool
+	// Archived only lists archived or not archived chats if set
+	Archived *bool
+}
+
+// MessageCursor points at a message in the history of a chat. Messages are ordered by
+// timestamp, the id makes the order stable for messages that were sent at the same time
+type MessageCursor struct {
+	Timestamp time.Time
+	ID        uuid.UUID
+}
+
+// MessagePageQuery selects a page of messages, only one of Around, Before and After is used
+type MessagePageQuery struct {
+	Around *uuid.UUID
+	Before string
+	After  string
+	Limit  int
+}
+
+// MessagePage is a page of chat messages in chronological order
+type MessagePage struct {
+	Messages []Message `json:"messages"`
+	// PrevCursor loads the older messages when passed as before, it is empty if there are none
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	// NextCursor loads the newer messages when passed as after, it is empty if there are none
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SearchQuery describes a full text search over the messages of some chats
+type SearchQuery struct {
+	Query       string
+	ChatIDs     []uuid.UUID
+	SenderID    *uuid.UUID
+	From        *time.Time
+	To          *time.Time
+	HasMedia    bool
+	RepliesOnly bool
+	Limit       int
+	Offset      int
+}
+
+type SearchResult struct {
+	Message Message `json:"message"`
+	// Snippet is the part of the message around the first match with all matches highlighted
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+type SearchResults struct {
+	Results []SearchResult `json:"results"`
+	Total   int64          `json:"total"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+}
+
+const (
+	EVENT_CHAT_READ = "chat_read"
+)
+
+// Event is a realtime notification for the members of a chat that is not a message itself.
+// Events are picked up by the gateway and only kept for a short time
+type Event struct {
+	ID        uuid.UUID `json:"id" bson:"_id"`
+	Type      string    `json:"type" bson:"type"`
+	ChatID    uuid.UUID `json:"chat_id" bson:"chat_id"`
+	UserID    uuid.UUID `json:"user" bson:"user"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+
+	// ReadUpTo and Count are set for chat_read events
+	ReadUpTo *time.Time `json:"read_up_to,omitempty" bson:"read_up_to,omitempty"`
+	Count    int64      `json:"count" bson:"count"`
 }
 
 type User struct {
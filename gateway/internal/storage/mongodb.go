@@ -19,6 +19,7 @@ type MongoDBStorage struct {
 	// MongoDB client
 	chatsCollection    *mongo.Collection
 	messagesCollection *mongo.Collection
+	eventsCollection   *mongo.Collection
 }
 
 func NewMongoDBStorage(connectionURI string) (*MongoDBStorage, error) {
@@ -28,6 +29,7 @@ func NewMongoDBStorage(connectionURI string) (*MongoDBStorage, error) {
 	}
 	chats := client.Database(DB_NAME).Collection("chats")
 	messages := client.Database(DB_NAME).Collection("messages")
+	events := client.Database(DB_NAME).Collection("events")
 
 	// ensure indexes
 	_, err = messages.Indexes().CreateOne(context.Background(), mongo.IndexModel{
@@ -43,6 +45,7 @@ func NewMongoDBStorage(connectionURI string) (*MongoDBStorage, error) {
 	return &MongoDBStorage{
 		chatsCollection:    chats,
 		messagesCollection: messages,
+		eventsCollection:   events,
 	}, nil
 }
 
@@ -81,3 +84,20 @@ func (m *MongoDBStorage) GetMessages(time time.Time) ([]utils.Message, error) {
 	}
 	return messages, nil
 }
+
+func (m *MongoDBStorage) GetEvents(time time.Time) ([]utils.Event, error) {
+	filter := bson.M{"timestamp": bson.M{"$gte": time}}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+	ctx := context.Background()
+	result, err := m.eventsCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []utils.Event{}
+	err = result.All(ctx, &events)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
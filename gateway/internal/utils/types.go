@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 const VERSION = "1.2.0"
@@ -20,6 +21,102 @@ type Message struct {
 	Read      bool        `json:"read" bson:"read"`
 	ReplyTo   *uuid.UUID  `json:"reply_to" bson:"reply_to"`
 	Deleted   bool        `json:"deleted" bson:"deleted"`
+	Reactions []Reaction  `json:"reactions" bson:"reactions"`
+
+	ThreadID    *uuid.UUID `json:"thread_id" bson:"thread_id"`
+	ReplyCount  int        `json:"reply_count" bson:"reply_count"`
+	LastReplyAt *time.Time `json:"last_reply_at" bson:"last_reply_at"`
+
+	ReadBy map[string]time.Time `json:"read_by" bson:"read_by"`
+
+	Edited    bool       `json:"edited" bson:"edited"`
+	EditCount int        `json:"edit_count" bson:"edit_count"`
+	EditedAt  *time.Time `json:"edited_at" bson:"edited_at"`
+
+	Poll *Poll `json:"poll,omitempty" bson:"poll"`
+
+	ForwardedFrom *ForwardedFrom `json:"forwarded_from,omitempty" bson:"forwarded_from"`
+
+	Previews []LinkPreview `json:"previews,omitempty" bson:"previews"`
+
+	Kind    string          `json:"kind" bson:"kind"`
+	Payload *MessagePayload `json:"payload,omitempty" bson:"payload"`
+}
+
+// UnmarshalBSON reads messages stored before messages had a kind as text messages
+func (m *Message) UnmarshalBSON(data []byte) error {
+	type message Message
+	err := bson.Unmarshal(data, (*message)(m))
+	if err != nil {
+		return err
+	}
+
+	if m.Kind == "" {
+		m.Kind = "text"
+	}
+	return nil
+}
+
+type MessagePayload struct {
+	System  *SystemPayload  `json:"system,omitempty" bson:"system"`
+	Command *CommandPayload `json:"command,omitempty" bson:"command"`
+	AI      *AIPayload      `json:"ai,omitempty" bson:"ai"`
+}
+
+type SystemPayload struct {
+	Event     string      `json:"event" bson:"event"`
+	Actor     *uuid.UUID  `json:"actor,omitempty" bson:"actor"`
+	Users     []uuid.UUID `json:"users,omitempty" bson:"users"`
+	MessageID *uuid.UUID  `json:"message_id,omitempty" bson:"message_id"`
+	Command   string      `json:"command,omitempty" bson:"command"`
+}
+
+type CommandPayload struct {
+	Name string   `json:"name" bson:"name"`
+	Args []string `json:"args" bson:"args"`
+}
+
+type AIPayload struct {
+	Complete bool `json:"complete" bson:"complete"`
+	Failed   bool `json:"failed,omitempty" bson:"failed"`
+}
+
+type LinkPreview struct {
+	URL         string `json:"url" bson:"url"`
+	Title       string `json:"title" bson:"title"`
+	Description string `json:"description,omitempty" bson:"description"`
+	Image       string `json:"image,omitempty" bson:"image"`
+	SiteName    string `json:"site_name,omitempty" bson:"site_name"`
+}
+
+type ForwardedFrom struct {
+	MessageID uuid.UUID `json:"message_id" bson:"message_id"`
+	SenderID  uuid.UUID `json:"sender" bson:"sender"`
+	ChatID    uuid.UUID `json:"chat_id" bson:"chat_id"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+}
+
+type Poll struct {
+	Question       string       `json:"question" bson:"question"`
+	Options        []PollOption `json:"options" bson:"options"`
+	MultipleChoice bool         `json:"multiple_choice" bson:"multiple_choice"`
+	Anonymous      bool         `json:"anonymous" bson:"anonymous"`
+	ClosesAt       *time.Time   `json:"closes_at" bson:"closes_at"`
+	Closed         bool         `json:"closed" bson:"closed"`
+	ClosedAt       *time.Time   `json:"closed_at" bson:"closed_at"`
+	Voters         int          `json:"voters" bson:"voters"`
+}
+
+type PollOption struct {
+	Text   string      `json:"text" bson:"text"`
+	Votes  int         `json:"votes" bson:"votes"`
+	Voters []uuid.UUID `json:"voters" bson:"voters"`
+}
+
+type Reaction struct {
+	Emoji     string    `json:"emoji" bson:"emoji"`
+	UserID    uuid.UUID `json:"user" bson:"user"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
 }
 
 type Chat struct {
@@ -32,6 +129,16 @@ type Chat struct {
 	LastActive time.Time   `json:"last_active" bson:"last_active"`
 }
 
+type Event struct {
+	ID        uuid.UUID  `json:"id" bson:"_id"`
+	Type      string     `json:"type" bson:"type"`
+	ChatID    uuid.UUID  `json:"chat_id" bson:"chat_id"`
+	UserID    uuid.UUID  `json:"user" bson:"user"`
+	Timestamp time.Time  `json:"timestamp" bson:"timestamp"`
+	ReadUpTo  *time.Time `json:"read_up_to,omitempty" bson:"read_up_to,omitempty"`
+	Count     int64      `json:"count" bson:"count"`
+}
+
 type User struct {
 	ID        uuid.UUID `json:"id" bson:"_id"`
 	Password  string    `json:"password" bson:"password"`
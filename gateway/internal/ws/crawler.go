@@ -42,10 +42,18 @@ func (m *MessageCrawler) Run() {
 			logger.Err(err).Msg("error while fetching the latest messages")
 			continue
 		}
+		events, err := m.storage.GetEvents(m.lastUpdate)
+		if err != nil {
+			logger.Err(err).Msg("error while fetching the latest events")
+			continue
+		}
 		chatIds := []uuid.UUID{}
 		for _, message := range messages {
 			chatIds = append(chatIds, message.ChatID)
 		}
+		for _, event := range events {
+			chatIds = append(chatIds, event.ChatID)
+		}
 		chats, err := m.storage.GetChat(chatIds)
 		if err != nil {
 			logger.Err(err).Msg("error while fetching the latest chats")
@@ -80,6 +88,23 @@ func (m *MessageCrawler) Run() {
 			m.hub.broadcast <- boradcastMsg
 		}
 
+		for _, event := range events {
+
+			bytes, err := json.Marshal(event)
+			if err != nil {
+				logger.Err(err).Msg("error while marshaling event")
+				continue
+			}
+
+			logger.Debug().
+				Str("event", string(bytes)).
+				Msg("broadcasting event")
+			m.hub.broadcast <- BroadCastMessage{
+				Bytes:    bytes,
+				Receiver: chatMap[event.ChatID],
+			}
+		}
+
 		m.lastUpdate = time.Now()
 	}
 }